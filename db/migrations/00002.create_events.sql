@@ -0,0 +1,38 @@
+BEGIN;
+
+-- The generator restarts the event IDs on every run, an event is identified by its run and ID
+CREATE TABLE events (
+    seq bigserial PRIMARY KEY,
+    run_id text NOT NULL DEFAULT '',
+    id bigint NOT NULL,
+    player_id bigint NOT NULL,
+    game_id bigint,
+    type text NOT NULL,
+    amount bigint,
+    currency text,
+    amount_eur bigint,
+    has_won boolean NOT NULL DEFAULT false,
+    description text NOT NULL DEFAULT '',
+    payload jsonb NOT NULL,
+    created_at timestamptz NOT NULL,
+    stored_at timestamptz NOT NULL DEFAULT now(),
+    UNIQUE (run_id, id)
+);
+
+CREATE INDEX events_player_id_created_at_idx ON events (player_id, created_at);
+CREATE INDEX events_game_id_created_at_idx ON events (game_id, created_at);
+CREATE INDEX events_type_created_at_idx ON events (type, created_at);
+CREATE INDEX events_created_at_seq_idx ON events (created_at, seq);
+
+-- The event store is append-only, stored events can not be changed or removed
+CREATE FUNCTION events_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'events table is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER events_append_only
+    BEFORE UPDATE OR DELETE ON events
+    FOR EACH ROW EXECUTE FUNCTION events_append_only();
+
+COMMIT;
@@ -26,6 +26,15 @@ var SmallestUnit = map[string]float64{
 	"BTC": 0.00000001, // 1 satoshi
 }
 
+// Define the largest amount of one event for each currency, in the smallest unit
+var MaxAmount = map[string]int{
+	"EUR": 100_000_000,    // 1,000,000 EUR
+	"USD": 100_000_000,    // 1,000,000 USD
+	"GBP": 100_000_000,    // 1,000,000 GBP
+	"NZD": 100_000_000,    // 1,000,000 NZD
+	"BTC": 10_000_000_000, // 100 BTC
+}
+
 type ExchangeRateResponse struct {
 	Success bool          `json:"success"`
 	Query   QueryResponse `json:"query"`
@@ -8,10 +8,13 @@ import (
 )
 
 const (
-	GAME_START = "game_start"
-	BET        = "bet"
-	DEPOSIT    = "deposit"
-	GAME_STOP  = "game_stop"
+	GAME_START   = "game_start"
+	BET          = "bet"
+	DEPOSIT      = "deposit"
+	GAME_STOP    = "game_stop"
+	WIN          = "win"
+	WITHDRAWAL   = "withdrawal"
+	BONUS_CREDIT = "bonus_credit"
 )
 
 var EventTypes = []string{
@@ -19,33 +22,45 @@ var EventTypes = []string{
 	BET,
 	DEPOSIT,
 	GAME_STOP,
+	WIN,
+	WITHDRAWAL,
+	BONUS_CREDIT,
 }
 
 type Event struct {
 	ID       int `json:"id"`
 	PlayerID int `json:"player_id"`
 
-	// Except for `deposit`.
+	// Only for types `game_start`, `bet`, `game_stop` and `win`.
 	GameID int `json:"game_id,omitempty"`
 
 	Type string `json:"type"`
 
 	// Smallest possible unit for the given currency.
 	// Examples: 300 = 3.00 EUR, 1 = 0.00000001 BTC.
-	// Only for types `bet` and `deposit`.
+	// Only for types `bet`, `deposit`, `win` (payout), `withdrawal` and `bonus_credit`.
 	Amount int `json:"amount,omitempty"`
 
-	// Only for types `bet` and `deposit`.
+	// Only for types `bet`, `deposit`, `win`, `withdrawal` and `bonus_credit`.
 	Currency string `json:"currency,omitempty"`
 
 	// Only for type `bet`.
 	HasWon bool `json:"has_won,omitempty"`
 
+	// Only for type `win`, ID of the won bet.
+	BetID int `json:"bet_id,omitempty"`
+
 	CreatedAt time.Time `json:"created_at"`
 
 	AmountEUR   int    `json:"amount_eur,omitempty"`
 	Player      Player `json:"player,omitempty"`
 	Description string `json:"description"`
+
+	// Only for events with a fault injected by the generator.
+	Fault string `json:"fault,omitempty"`
+
+	// Generator run of the event, the event IDs are unique only within a run.
+	RunID string `json:"run_id,omitempty"`
 }
 
 // Set event description field
@@ -63,12 +78,27 @@ func (e *Event) SetDescription() {
 	case BET:
 		e.Description = fmt.Sprintf(`%s placed bet of %s on game "%s" on %s`, playerDesc, currDesc, gameDesc, timeDesc)
 	case DEPOSIT:
-		e.Description = fmt.Sprintf(`%s placed deposit of %s on game "%s" on %s`, playerDesc, currDesc, gameDesc, timeDesc)
+		e.Description = fmt.Sprintf(`%s made a deposit of %s on %s`, playerDesc, currDesc, timeDesc)
+	case WIN:
+		e.Description = fmt.Sprintf(`%s won %s with bet #%d on game "%s" on %s`, playerDesc, currDesc, e.BetID, gameDesc, timeDesc)
+	case WITHDRAWAL:
+		e.Description = fmt.Sprintf(`%s made a withdrawal of %s on %s`, playerDesc, currDesc, timeDesc)
+	case BONUS_CREDIT:
+		e.Description = fmt.Sprintf(`%s received a bonus credit of %s on %s`, playerDesc, currDesc, timeDesc)
 	default:
 		log.Printf("Unknown event type %v", e.Type)
 	}
 }
 
+// HasAmount reports whether the event type carries an amount
+func (e *Event) HasAmount() bool {
+	switch e.Type {
+	case BET, DEPOSIT, WIN, WITHDRAWAL, BONUS_CREDIT:
+		return true
+	}
+	return false
+}
+
 func (e *Event) getPlayerDesc() string {
 	if e.Player.IsZero() {
 		return fmt.Sprintf("Player ID %d", e.PlayerID)
@@ -0,0 +1,148 @@
+package casino
+
+import (
+	"fmt"
+	"time"
+)
+
+// Validation rules, an invalid event breaks exactly one rule (the first one checked)
+const (
+	RULE_UNKNOWN_TYPE        = "unknown_type"
+	RULE_MISSING_ID          = "missing_id"
+	RULE_MISSING_PLAYER_ID   = "missing_player_id"
+	RULE_MISSING_CREATED_AT  = "missing_created_at"
+	RULE_FUTURE_CREATED_AT   = "future_created_at"
+	RULE_REQUIRED_GAME_ID    = "required_game_id"
+	RULE_FORBIDDEN_GAME_ID   = "forbidden_game_id"
+	RULE_UNKNOWN_GAME        = "unknown_game"
+	RULE_REQUIRED_CURRENCY   = "required_currency"
+	RULE_FORBIDDEN_CURRENCY  = "forbidden_currency"
+	RULE_UNKNOWN_CURRENCY    = "unknown_currency"
+	RULE_FORBIDDEN_AMOUNT    = "forbidden_amount"
+	RULE_AMOUNT_OUT_OF_RANGE = "amount_out_of_range"
+	RULE_FORBIDDEN_HAS_WON   = "forbidden_has_won"
+	RULE_REQUIRED_BET_ID     = "required_bet_id"
+	RULE_FORBIDDEN_BET_ID    = "forbidden_bet_id"
+	RULE_OVERSIZED_PAYLOAD   = "oversized_payload"
+)
+
+const (
+	// Events may be created slightly in the future because of clock skew between the services
+	MAX_CLOCK_SKEW = 1 * time.Minute
+
+	// Longest accepted description and player email
+	MAX_DESCRIPTION_LENGTH = 4 * 1024
+	MAX_EMAIL_LENGTH       = 320
+)
+
+type ValidationError struct {
+	Rule    string
+	Message string
+}
+
+func (ve *ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", ve.Rule, ve.Message)
+}
+
+// ValidationRule returns the broken rule of the validation error
+func ValidationRule(err error) string {
+	if validationErr, ok := err.(*ValidationError); ok {
+		return validationErr.Rule
+	}
+	return "invalid"
+}
+
+func invalid(rule, format string, args ...interface{}) *ValidationError {
+	return &ValidationError{
+		Rule:    rule,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+// Fields of each event type
+type typeRules struct {
+	gameID bool // Required (true) or forbidden (false)
+	amount bool // Amount and currency required (true) or forbidden (false)
+	hasWon bool // Allowed (true) or forbidden (false), a lost bet has no has_won
+	betID  bool // Required (true) or forbidden (false)
+}
+
+var eventTypeRules = map[string]typeRules{
+	GAME_START:   {gameID: true},
+	GAME_STOP:    {gameID: true},
+	BET:          {gameID: true, amount: true, hasWon: true},
+	DEPOSIT:      {amount: true},
+	WIN:          {gameID: true, amount: true, betID: true},
+	WITHDRAWAL:   {amount: true},
+	BONUS_CREDIT: {amount: true},
+}
+
+// Validate checks the event against the rules of its type and returns a *ValidationError if the event is invalid
+func (e *Event) Validate() error {
+	rules, ok := eventTypeRules[e.Type]
+	if !ok {
+		return invalid(RULE_UNKNOWN_TYPE, "unknown event type %q", e.Type)
+	}
+
+	if e.ID <= 0 {
+		return invalid(RULE_MISSING_ID, "event ID must be positive")
+	}
+	if e.PlayerID <= 0 {
+		return invalid(RULE_MISSING_PLAYER_ID, "player ID must be positive")
+	}
+
+	if e.CreatedAt.IsZero() {
+		return invalid(RULE_MISSING_CREATED_AT, "created_at is required")
+	}
+	if e.CreatedAt.After(time.Now().Add(MAX_CLOCK_SKEW)) {
+		return invalid(RULE_FUTURE_CREATED_AT, "created_at %s is in the future", e.CreatedAt.Format(time.RFC3339))
+	}
+
+	if rules.gameID {
+		if e.GameID == 0 {
+			return invalid(RULE_REQUIRED_GAME_ID, "%s requires game_id", e.Type)
+		}
+		if _, ok := Games[e.GameID]; !ok {
+			return invalid(RULE_UNKNOWN_GAME, "unknown game %d", e.GameID)
+		}
+	} else if e.GameID != 0 {
+		return invalid(RULE_FORBIDDEN_GAME_ID, "%s can not have game_id", e.Type)
+	}
+
+	if rules.amount {
+		if e.Currency == "" {
+			return invalid(RULE_REQUIRED_CURRENCY, "%s requires currency", e.Type)
+		}
+		maxAmount, ok := MaxAmount[e.Currency]
+		if !ok {
+			return invalid(RULE_UNKNOWN_CURRENCY, "unknown currency %q", e.Currency)
+		}
+		if e.Amount <= 0 || e.Amount > maxAmount {
+			return invalid(RULE_AMOUNT_OUT_OF_RANGE, "amount %d %s is not in (0, %d]", e.Amount, e.Currency, maxAmount)
+		}
+	} else {
+		if e.Currency != "" {
+			return invalid(RULE_FORBIDDEN_CURRENCY, "%s can not have currency", e.Type)
+		}
+		if e.Amount != 0 || e.AmountEUR != 0 {
+			return invalid(RULE_FORBIDDEN_AMOUNT, "%s can not have amount", e.Type)
+		}
+	}
+
+	if !rules.hasWon && e.HasWon {
+		return invalid(RULE_FORBIDDEN_HAS_WON, "%s can not have has_won", e.Type)
+	}
+
+	if rules.betID && e.BetID <= 0 {
+		return invalid(RULE_REQUIRED_BET_ID, "%s requires bet_id", e.Type)
+	}
+	if !rules.betID && e.BetID != 0 {
+		return invalid(RULE_FORBIDDEN_BET_ID, "%s can not have bet_id", e.Type)
+	}
+
+	if len(e.Description) > MAX_DESCRIPTION_LENGTH || len(e.Player.Email) > MAX_EMAIL_LENGTH {
+		return invalid(RULE_OVERSIZED_PAYLOAD, "description or player email is too long")
+	}
+
+	return nil
+}
@@ -0,0 +1,72 @@
+package casino
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// validEvent returns a valid event of the type
+func validEvent(eventType string) Event {
+	event := Event{ID: 1, PlayerID: 10, Type: eventType, CreatedAt: time.Now()}
+	rules := eventTypeRules[eventType]
+	if rules.gameID {
+		event.GameID = 100
+	}
+	if rules.amount {
+		event.Amount, event.Currency = 500, "EUR"
+	}
+	if rules.betID {
+		event.BetID = 1
+	}
+	return event
+}
+
+func TestValidateAcceptsEachType(t *testing.T) {
+	for _, eventType := range EventTypes {
+		event := validEvent(eventType)
+		if err := event.Validate(); err != nil {
+			t.Errorf("valid %s rejected: %v", eventType, err)
+		}
+	}
+
+	wonBet := validEvent(BET)
+	wonBet.HasWon = true
+	if err := wonBet.Validate(); err != nil {
+		t.Errorf("won bet rejected: %v", err)
+	}
+}
+
+func TestValidateRules(t *testing.T) {
+	for _, tc := range []struct {
+		rule      string
+		eventType string
+		change    func(*Event)
+	}{
+		{RULE_UNKNOWN_TYPE, BET, func(e *Event) { e.Type = "jackpot" }},
+		{RULE_MISSING_ID, BET, func(e *Event) { e.ID = 0 }},
+		{RULE_MISSING_PLAYER_ID, DEPOSIT, func(e *Event) { e.PlayerID = -1 }},
+		{RULE_MISSING_CREATED_AT, GAME_START, func(e *Event) { e.CreatedAt = time.Time{} }},
+		{RULE_FUTURE_CREATED_AT, GAME_STOP, func(e *Event) { e.CreatedAt = time.Now().Add(MAX_CLOCK_SKEW + time.Minute) }},
+		{RULE_REQUIRED_GAME_ID, BET, func(e *Event) { e.GameID = 0 }},
+		{RULE_FORBIDDEN_GAME_ID, DEPOSIT, func(e *Event) { e.GameID = 100 }},
+		{RULE_UNKNOWN_GAME, WIN, func(e *Event) { e.GameID = 999 }},
+		{RULE_REQUIRED_CURRENCY, WITHDRAWAL, func(e *Event) { e.Currency = "" }},
+		{RULE_FORBIDDEN_CURRENCY, GAME_START, func(e *Event) { e.Currency = "EUR" }},
+		{RULE_UNKNOWN_CURRENCY, BONUS_CREDIT, func(e *Event) { e.Currency = "XYZ" }},
+		{RULE_FORBIDDEN_AMOUNT, GAME_STOP, func(e *Event) { e.Amount = 100 }},
+		{RULE_AMOUNT_OUT_OF_RANGE, BET, func(e *Event) { e.Amount = 0 }},
+		{RULE_AMOUNT_OUT_OF_RANGE, DEPOSIT, func(e *Event) { e.Amount = MaxAmount["EUR"] + 1 }},
+		{RULE_FORBIDDEN_HAS_WON, WIN, func(e *Event) { e.HasWon = true }},
+		{RULE_REQUIRED_BET_ID, WIN, func(e *Event) { e.BetID = 0 }},
+		{RULE_FORBIDDEN_BET_ID, BET, func(e *Event) { e.BetID = 1 }},
+		{RULE_OVERSIZED_PAYLOAD, DEPOSIT, func(e *Event) { e.Description = strings.Repeat("a", MAX_DESCRIPTION_LENGTH+1) }},
+		{RULE_OVERSIZED_PAYLOAD, BET, func(e *Event) { e.Player.Email = strings.Repeat("a", MAX_EMAIL_LENGTH+1) }},
+	} {
+		event := validEvent(tc.eventType)
+		tc.change(&event)
+		if got := ValidationRule(event.Validate()); got != tc.rule {
+			t.Errorf("%s breaking %s: rule %q", tc.eventType, tc.rule, got)
+		}
+	}
+}
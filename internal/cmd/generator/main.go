@@ -2,16 +2,24 @@ package main
 
 import (
 	"context"
+	"flag"
+	"fmt"
 	"log"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
 
+	"github.com/Bitstarz-eng/event-processing-challenge/internal/casino"
+	"github.com/Bitstarz-eng/event-processing-challenge/internal/db"
+	"github.com/Bitstarz-eng/event-processing-challenge/internal/generator"
 	"github.com/Bitstarz-eng/event-processing-challenge/internal/listener"
 	"github.com/Bitstarz-eng/event-processing-challenge/internal/publisher"
 	rds "github.com/Bitstarz-eng/event-processing-challenge/internal/redis"
+	"github.com/Bitstarz-eng/event-processing-challenge/internal/replay"
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq" // PostgreSQL driver
 )
@@ -25,15 +33,151 @@ func init() {
 }
 
 func main() {
-	var wg sync.WaitGroup
+	if len(os.Args) > 1 && os.Args[1] == "replay" {
+		runReplay(os.Args[2:])
+		return
+	}
+	runGenerator(os.Args[1:])
+}
+
+// Publish randomly generated events for 1 second, or at the target rate in load mode
+func runGenerator(args []string) {
+	flags := flag.NewFlagSet("generator", flag.ExitOnError)
+	seed := flags.Int64("seed", 0, "seed of the generator, the same seed generates the same events (0 picks a random seed)")
+	record := flags.String("record", "", "record the generated events to this JSON-lines file")
+	scenarioPath := flags.String("scenario", "", "YAML or JSON scenario file with the distributions of the generated events")
+	sessions := flags.Bool("sessions", false, "simulate player sessions (deposit, game_start, bets, game_stop) with balances")
+	faults := flags.Float64("faults", 0, "percentage (0-100) of the generated events with an injected fault")
+	faultKinds := flags.String("fault-kinds", "", "comma separated faults to inject (all faults by default): "+strings.Join(generator.Faults, ", "))
+
+	load := flags.Bool("load", false, "load mode: generate events at the target rate and report the achieved rate and latencies")
+	profile := flags.String("profile", generator.CONSTANT, "load rate profile: constant, ramp, step or burst")
+	rate := flags.Float64("rate", 100, "load events per second (start rate of ramp and step, base rate of burst)")
+	peakRate := flags.Float64("peak-rate", 1000, "load end rate of ramp and step, rate of the bursts")
+	steps := flags.Int("steps", 5, "number of steps of the step profile")
+	burstEvery := flags.Duration("burst-every", 10*time.Second, "period of the bursts")
+	burstLength := flags.Duration("burst-length", 2*time.Second, "length of one burst")
+	duration := flags.Duration("duration", 30*time.Second, "length of the load run")
+	producers := flags.Int("producers", 4, "number of concurrent load producers")
+	buffer := flags.Int("buffer", 1000, "events buffered for the publisher, events which do not fit are dropped")
+	flags.Parse(args)
+
+	var scenario *generator.Scenario
+	if *scenarioPath != "" {
+		var err error
+		scenario, err = generator.LoadScenario(*scenarioPath)
+		if err != nil {
+			log.Fatalf("Error loading scenario: %v", err)
+		}
+	}
+
+	opts := generator.Options{
+		Seed:       *seed,
+		RecordPath: *record,
+		Scenario:   scenario,
+		Sessions:   *sessions,
+		Faults: generator.FaultOptions{
+			Percent: *faults,
+			Kinds:   splitList(*faultKinds),
+		},
+	}
+
+	if *load {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		eventCh, report, err := generator.GenerateLoad(ctx, opts, generator.LoadOptions{
+			Profile:     *profile,
+			Rate:        *rate,
+			PeakRate:    *peakRate,
+			Steps:       *steps,
+			BurstEvery:  *burstEvery,
+			BurstLength: *burstLength,
+			Duration:    *duration,
+			Producers:   *producers,
+			BufferSize:  *buffer,
+		})
+		if err != nil {
+			log.Fatalf("Error starting load generator: %v", err)
+		}
+
+		run(cancel, eventCh, report)
+		return
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
 	defer cancel()
 
+	eventCh, err := generator.Generate(ctx, opts)
+	if err != nil {
+		log.Fatalf("Error starting generator: %v", err)
+	}
+
+	run(cancel, eventCh, nil)
+}
+
+// Publish the events from a JSON-lines file or the event store
+func runReplay(args []string) {
+	flags := flag.NewFlagSet("replay", flag.ExitOnError)
+	file := flags.String("file", "", "JSON-lines file with the events to replay")
+	fromStore := flags.Bool("store", false, "replay the events from the event store")
+	speed := flags.Float64("speed", 1, "replay speed: 1 keeps the original pacing, N is N times faster, 0 is as fast as possible")
+	from := flags.String("from", "", "replay events created at or after this RFC3339 time")
+	to := flags.String("to", "", "replay events created before this RFC3339 time")
+	players := flags.String("player", "", "comma separated player IDs to replay")
+	types := flags.String("type", "", "comma separated event types to replay")
+	flags.Parse(args)
+
+	if (*file == "") == !*fromStore {
+		log.Fatal("Replay needs exactly one source: -file or -store")
+	}
+	if *speed < 0 {
+		log.Fatal("Replay speed can not be negative")
+	}
+
+	opts := replay.Options{
+		Speed: *speed,
+		Filter: replay.Filter{
+			From:      parseTime("from", *from),
+			To:        parseTime("to", *to),
+			PlayerIDs: parseIDs(*players),
+			Types:     parseTypes(*types),
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var eventCh <-chan casino.Event
+	var err error
+	if *fromStore {
+		eventCh, err = replay.FromStore(ctx, db.GetDB(), opts)
+	} else {
+		eventCh, err = replay.FromFile(ctx, *file, opts)
+	}
+	if err != nil {
+		log.Fatalf("Error starting replay: %v", err)
+	}
+
+	run(cancel, eventCh, nil)
+}
+
+// Publish the events and serve the statistics until the termination signal.
+// With a load report, the report is printed once all events are published.
+func run(cancel context.CancelFunc, eventCh <-chan casino.Event, report *generator.LoadReport) {
+	var wg sync.WaitGroup
+
 	// Connect to Redis and start publishing events
 	publisher := publisher.NewPublisher()
+	if report != nil {
+		publisher.OnPublish = report.RecordPublish
+	}
+	published := make(chan struct{})
 	wg.Add(1)
-	go publisher.StartPublishing(ctx, &wg)
+	go func() {
+		publisher.StartPublishing(eventCh, &wg)
+		close(published)
+	}()
 
 	// Listen localhost/materialized endpoint for statistics
 	materialized := listener.NewMaterializedListener(publisher)
@@ -45,12 +189,15 @@ func main() {
 	signal.Notify(sigChan, syscall.SIGTERM, syscall.SIGINT)
 
 	select {
-	case <-ctx.Done():
-		log.Println("Stop publishing, Context timeout")
+	case <-published:
+		log.Println("Stop publishing, no more events")
 		publisher.ShowStats()
+		if report != nil {
+			fmt.Println(report)
+		}
 	case sig := <-sigChan:
 		log.Printf("Received SIGTERM/SIGINT signal: %v\n", sig)
-		// Cancel the context to stop the publisher
+		// Cancel the context to stop the event source
 		cancel()
 	}
 
@@ -58,3 +205,45 @@ func main() {
 	wg.Wait()
 	rds.Close()
 }
+
+func parseTime(name, value string) time.Time {
+	if value == "" {
+		return time.Time{}
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		log.Fatalf("Invalid -%s time: %v", name, err)
+	}
+	return t
+}
+
+func parseIDs(value string) map[int]bool {
+	ids := make(map[int]bool)
+	for _, field := range splitList(value) {
+		id, err := strconv.Atoi(field)
+		if err != nil {
+			log.Fatalf("Invalid player ID %q: %v", field, err)
+		}
+		ids[id] = true
+	}
+	return ids
+}
+
+func parseTypes(value string) map[string]bool {
+	types := make(map[string]bool)
+	for _, field := range splitList(value) {
+		types[field] = true
+	}
+	return types
+}
+
+func splitList(value string) []string {
+	var fields []string
+	for _, field := range strings.Split(value, ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GetString returns the value of the environment variable or the default value if it is not set
+func GetString(key, defaultValue string) string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}
+
+// GetInt returns the integer value of the environment variable or the default value if it is not set
+func GetInt(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	intValue, err := strconv.Atoi(value)
+	if err != nil {
+		log.Fatalf("Invalid integer value for %s: %v", key, err)
+	}
+	return intValue
+}
+
+// GetDuration returns the duration value (e.g. 500ms, 5s) of the environment variable or the default value if it is not set
+func GetDuration(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		log.Fatalf("Invalid duration value for %s: %v", key, err)
+	}
+	return duration
+}
+
+// GetDurations returns the comma separated durations (e.g. 1m,5m) of the environment variable or the default value if it is not set
+func GetDurations(key string, defaultValue []time.Duration) []time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	var durations []time.Duration
+	for _, item := range strings.Split(value, ",") {
+		duration, err := time.ParseDuration(strings.TrimSpace(item))
+		if err != nil {
+			log.Fatalf("Invalid duration value for %s: %v", key, err)
+		}
+		durations = append(durations, duration)
+	}
+	return durations
+}
@@ -0,0 +1,205 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Bitstarz-eng/event-processing-challenge/internal/casino"
+	"github.com/lib/pq"
+)
+
+// Columns inserted for each event
+var eventColumns = []string{
+	"run_id",
+	"id",
+	"player_id",
+	"game_id",
+	"type",
+	"amount",
+	"currency",
+	"amount_eur",
+	"has_won",
+	"description",
+	"payload",
+	"created_at",
+}
+
+// EventFilter narrows down the stored events, zero-value fields are ignored
+type EventFilter struct {
+	PlayerIDs []int
+	GameID    int
+	Types     []string
+	From      time.Time
+	To        time.Time
+	Limit     int
+	Newest    bool         // Newest events first, e.g. the latest Limit events
+	After     *EventCursor // Only the events after the cursor, e.g. the next page (not with Newest)
+}
+
+// EventCursor is the position of a stored event in the order of the events
+type EventCursor struct {
+	CreatedAt time.Time
+	Seq       int64
+}
+
+// InsertEvents stores the batch of events and returns the number of inserted rows.
+// Events which are already stored (same run and event ID) are skipped.
+func (db *DB) InsertEvents(events []casino.Event) (int64, error) {
+	if len(events) == 0 {
+		return 0, nil
+	}
+
+	rows := make([]string, 0, len(events))
+	args := make([]interface{}, 0, len(events)*len(eventColumns))
+	for _, event := range events {
+		payload, err := json.Marshal(event)
+		if err != nil {
+			return 0, fmt.Errorf("marshal event %d: %w", event.ID, err)
+		}
+
+		placeholders := make([]string, len(eventColumns))
+		for i := range placeholders {
+			placeholders[i] = fmt.Sprintf("$%d", len(args)+i+1)
+		}
+		rows = append(rows, "("+strings.Join(placeholders, ", ")+")")
+
+		args = append(args,
+			event.RunID,
+			event.ID,
+			event.PlayerID,
+			nullInt(event.GameID),
+			event.Type,
+			nullInt(event.Amount),
+			nullString(event.Currency),
+			nullInt(event.AmountEUR),
+			event.HasWon,
+			event.Description,
+			payload,
+			event.CreatedAt,
+		)
+	}
+
+	query := fmt.Sprintf("INSERT INTO events (%s) VALUES %s ON CONFLICT (run_id, id) DO NOTHING",
+		strings.Join(eventColumns, ", "), strings.Join(rows, ", "))
+
+	result, err := db.conn.Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// QueryEvents returns the stored events matching the filter, ordered by creation time (newest first with Newest)
+func (db *DB) QueryEvents(filter EventFilter) ([]casino.Event, error) {
+	events, _, err := db.QueryEventsPage(filter)
+	return events, err
+}
+
+// QueryEventsPage returns the stored events matching the filter and the cursor of the last event, nil without events.
+// The next page are the events After the cursor.
+func (db *DB) QueryEventsPage(filter EventFilter) ([]casino.Event, *EventCursor, error) {
+	var conditions []string
+	var args []interface{}
+
+	addCondition := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if len(filter.PlayerIDs) == 1 {
+		addCondition("player_id = $%d", filter.PlayerIDs[0])
+	} else if len(filter.PlayerIDs) > 1 {
+		addCondition("player_id = ANY($%d)", pq.Array(filter.PlayerIDs))
+	}
+	if filter.GameID != 0 {
+		addCondition("game_id = $%d", filter.GameID)
+	}
+	if len(filter.Types) == 1 {
+		addCondition("type = $%d", filter.Types[0])
+	} else if len(filter.Types) > 1 {
+		addCondition("type = ANY($%d)", pq.Array(filter.Types))
+	}
+	if !filter.From.IsZero() {
+		addCondition("created_at >= $%d", filter.From)
+	}
+	if !filter.To.IsZero() {
+		addCondition("created_at < $%d", filter.To)
+	}
+	if filter.After != nil {
+		args = append(args, filter.After.CreatedAt, filter.After.Seq)
+		conditions = append(conditions, fmt.Sprintf("(created_at, seq) > ($%d, $%d)", len(args)-1, len(args)))
+	}
+
+	query := "SELECT created_at, seq, payload FROM events"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	if filter.Newest {
+		query += " ORDER BY created_at DESC, seq DESC"
+	} else {
+		query += " ORDER BY created_at, seq"
+	}
+	if filter.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", filter.Limit)
+	}
+
+	rows, err := db.conn.Query(query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var events []casino.Event
+	var cursor *EventCursor
+	for rows.Next() {
+		var position EventCursor
+		var payload []byte
+		if err := rows.Scan(&position.CreatedAt, &position.Seq, &payload); err != nil {
+			return nil, nil, err
+		}
+
+		var event casino.Event
+		if err := json.Unmarshal(payload, &event); err != nil {
+			return nil, nil, fmt.Errorf("unmarshal stored event: %w", err)
+		}
+		events = append(events, event)
+		cursor = &position
+	}
+	return events, cursor, rows.Err()
+}
+
+// GetEventsByPlayer returns all stored events of the player
+func (db *DB) GetEventsByPlayer(playerID int) ([]casino.Event, error) {
+	return db.QueryEvents(EventFilter{PlayerIDs: []int{playerID}})
+}
+
+// GetRecentEventsByPlayer returns the latest limit stored events of the player, newest first
+func (db *DB) GetRecentEventsByPlayer(playerID, limit int) ([]casino.Event, error) {
+	return db.QueryEvents(EventFilter{PlayerIDs: []int{playerID}, Limit: limit, Newest: true})
+}
+
+// GetEventsByGame returns all stored events of the game
+func (db *DB) GetEventsByGame(gameID int) ([]casino.Event, error) {
+	return db.QueryEvents(EventFilter{GameID: gameID})
+}
+
+// GetEventsByType returns all stored events of the event type
+func (db *DB) GetEventsByType(eventType string) ([]casino.Event, error) {
+	return db.QueryEvents(EventFilter{Types: []string{eventType}})
+}
+
+// GetEventsByTimeRange returns all stored events created in [from, to)
+func (db *DB) GetEventsByTimeRange(from, to time.Time) ([]casino.Event, error) {
+	return db.QueryEvents(EventFilter{From: from, To: to})
+}
+
+func nullInt(value int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(value), Valid: value != 0}
+}
+
+func nullString(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
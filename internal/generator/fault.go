@@ -0,0 +1,153 @@
+package generator
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/Bitstarz-eng/event-processing-challenge/internal/casino"
+)
+
+// Faults injected into the generated events, the fault is stored in the `fault` field of the event
+const (
+	FAULT_UNKNOWN_TYPE      = "unknown_type"
+	FAULT_UNKNOWN_CURRENCY  = "unknown_currency"
+	FAULT_NEGATIVE_AMOUNT   = "negative_amount"
+	FAULT_ZERO_AMOUNT       = "zero_amount"
+	FAULT_MISSING_GAME_ID   = "missing_game_id"
+	FAULT_DUPLICATE_ID      = "duplicate_id"
+	FAULT_OUT_OF_ORDER      = "out_of_order_timestamp"
+	FAULT_FUTURE_TIMESTAMP  = "future_timestamp"
+	FAULT_OVERSIZED_PAYLOAD = "oversized_payload"
+)
+
+var Faults = []string{
+	FAULT_UNKNOWN_TYPE,
+	FAULT_UNKNOWN_CURRENCY,
+	FAULT_NEGATIVE_AMOUNT,
+	FAULT_ZERO_AMOUNT,
+	FAULT_MISSING_GAME_ID,
+	FAULT_DUPLICATE_ID,
+	FAULT_OUT_OF_ORDER,
+	FAULT_FUTURE_TIMESTAMP,
+	FAULT_OVERSIZED_PAYLOAD,
+}
+
+const (
+	UNKNOWN_EVENT_TYPE = "jackpot_spin"
+	UNKNOWN_CURRENCY   = "XYZ"
+
+	// Out-of-order events are up to this much older than the current events
+	MAX_OUT_OF_ORDER_DELAY = 10 * time.Minute
+
+	// Far-future events are created this much after the current events
+	FUTURE_OFFSET = 365 * 24 * time.Hour
+
+	// Size of the description of oversized events, above the validation limit
+	// and far below the max line size of the replay, so recorded oversized events can be replayed
+	OVERSIZED_PAYLOAD_SIZE = 4 * casino.MAX_DESCRIPTION_LENGTH
+)
+
+type FaultOptions struct {
+	// Percentage (0-100) of the generated events with an injected fault
+	Percent float64
+
+	// Faults to inject, empty injects all Faults
+	Kinds []string
+}
+
+func (fo FaultOptions) validate() error {
+	if fo.Percent < 0 || fo.Percent > 100 {
+		return fmt.Errorf("fault percentage must be between 0 and 100")
+	}
+	for _, kind := range fo.Kinds {
+		if !isFault(kind) {
+			return fmt.Errorf("unknown fault %q", kind)
+		}
+	}
+	return nil
+}
+
+func isFault(kind string) bool {
+	for _, fault := range Faults {
+		if fault == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// faultInjector breaks a percentage of the events, everything else in the faulty event stays valid
+type faultInjector struct {
+	opts     FaultOptions
+	kinds    []string
+	scenario *scenarioRunner
+}
+
+func newFaultInjector(opts FaultOptions, scenario *scenarioRunner) *faultInjector {
+	kinds := opts.Kinds
+	if len(kinds) == 0 {
+		kinds = Faults
+	}
+	return &faultInjector{
+		opts:     opts,
+		kinds:    kinds,
+		scenario: scenario,
+	}
+}
+
+// Inject a random fault into the event with the configured probability
+func (fi *faultInjector) inject(rnd *rand.Rand, event *casino.Event) {
+	if rnd.Float64()*100 >= fi.opts.Percent {
+		return
+	}
+
+	fault := fi.kinds[rnd.Intn(len(fi.kinds))]
+	event.Fault = fault
+
+	switch fault {
+	case FAULT_UNKNOWN_TYPE:
+		event.Type = UNKNOWN_EVENT_TYPE
+	case FAULT_UNKNOWN_CURRENCY:
+		fi.toBet(rnd, event)
+		event.Currency = UNKNOWN_CURRENCY
+	case FAULT_NEGATIVE_AMOUNT:
+		fi.toBet(rnd, event)
+		event.Amount = -event.Amount
+	case FAULT_ZERO_AMOUNT:
+		fi.toBet(rnd, event)
+		event.Amount = 0
+	case FAULT_MISSING_GAME_ID:
+		fi.toBet(rnd, event)
+		event.GameID = 0
+	case FAULT_DUPLICATE_ID:
+		if event.ID > 1 {
+			event.ID = 1 + rnd.Intn(event.ID-1)
+		}
+	case FAULT_OUT_OF_ORDER:
+		event.CreatedAt = event.CreatedAt.Add(-time.Duration(1 + rnd.Int63n(int64(MAX_OUT_OF_ORDER_DELAY))))
+	case FAULT_FUTURE_TIMESTAMP:
+		event.CreatedAt = event.CreatedAt.Add(FUTURE_OFFSET)
+	case FAULT_OVERSIZED_PAYLOAD:
+		event.Description = strings.Repeat("x", OVERSIZED_PAYLOAD_SIZE)
+	}
+}
+
+// Turn the event into a valid bet, so only the injected fault is wrong
+func (fi *faultInjector) toBet(rnd *rand.Rand, event *casino.Event) {
+	if event.Type == casino.BET {
+		return
+	}
+
+	if event.GameID == 0 {
+		event.GameID = fi.scenario.randomGame(rnd)
+	}
+	if event.Currency == "" {
+		event.Currency = fi.scenario.randomCurrency(rnd)
+	}
+	if event.Amount <= 0 {
+		event.Amount = fi.scenario.randomAmount(rnd, event.Currency)
+	}
+	event.Type = casino.BET
+}
@@ -2,68 +2,228 @@ package generator
 
 import (
 	"context"
+	crand "crypto/rand"
+	"fmt"
+	"log"
 	"math/rand"
 	"time"
 
 	"github.com/Bitstarz-eng/event-processing-challenge/internal/casino"
 )
 
-func Generate(ctx context.Context) <-chan casino.Event {
+type Options struct {
+	// Seed of the random source, 0 picks a time based seed.
+	// The same seed generates the same sequence of events.
+	Seed int64
+
+	// JSON-lines file every generated event is recorded to, empty disables the recording
+	RecordPath string
+
+	// Distributions of the generated events, nil uses the DefaultScenario
+	Scenario *Scenario
+
+	// Simulate player sessions instead of independent random events.
+	// The event type weights of the scenario are not used in this mode.
+	Sessions bool
+
+	// Inject malformed and edge-case events
+	Faults FaultOptions
+}
+
+type generator struct {
+	runID    string
+	rnd      *rand.Rand
+	scenario *scenarioRunner
+	sessions *sessionSimulator
+	faults   *faultInjector
+	id       int
+
+	// Win of the previous bet, generated as the next event
+	pendingWin *casino.Event
+}
+
+// Create the generators sharing the scenario, the session players and the recording.
+// Each generator has its own random source seeded from the seed of the options.
+func newGenerators(opts Options, count int) ([]*generator, *Recorder, error) {
+	if err := opts.Faults.validate(); err != nil {
+		return nil, nil, err
+	}
+	// A scenario built in code is validated like a loaded one, the validation also parses the rate phase durations
+	if opts.Scenario != nil {
+		if err := opts.Scenario.validate(); err != nil {
+			return nil, nil, fmt.Errorf("invalid scenario: %w", err)
+		}
+	}
+
+	seed := opts.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	log.Printf("Generator seed: %d", seed)
+
+	// The IDs restart at 1 on every run, the run ID keeps the events of different runs apart
+	runID, err := newRunID()
+	if err != nil {
+		return nil, nil, err
+	}
+	log.Printf("Generator run: %s", runID)
+
+	var recorder *Recorder
+	if opts.RecordPath != "" {
+		var err error
+		recorder, err = NewRecorder(opts.RecordPath)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	scenario := opts.Scenario
+	if scenario == nil {
+		scenario = DefaultScenario()
+	}
+	runner := newScenarioRunner(scenario)
+
+	var sessions *sessionSimulator
+	if opts.Sessions {
+		sessions = newSessionSimulator(runner)
+	}
+
+	var faults *faultInjector
+	if opts.Faults.Percent > 0 {
+		faults = newFaultInjector(opts.Faults, runner)
+	}
+
+	generators := make([]*generator, count)
+	for i := range generators {
+		generators[i] = &generator{
+			runID:    runID,
+			rnd:      rand.New(rand.NewSource(seed + int64(i))),
+			scenario: runner,
+			sessions: sessions,
+			faults:   faults,
+		}
+	}
+	return generators, recorder, nil
+}
+
+func Generate(ctx context.Context, opts Options) (<-chan casino.Event, error) {
+	generators, recorder, err := newGenerators(opts, 1)
+	if err != nil {
+		return nil, err
+	}
+	g := generators[0]
+
 	eventCh := make(chan casino.Event)
-	var id int
 
 	go func() {
 		defer close(eventCh)
+		if recorder != nil {
+			defer recorder.Close()
+		}
 
 		for {
-			id++
+			g.id++
 
 			select {
 			case <-ctx.Done():
 				return
 			default:
-				eventCh <- generate(id)
+				event := g.generate(g.id)
+				if recorder != nil {
+					recorder.Record(event)
+				}
+				eventCh <- event
 			}
 
-			time.Sleep(time.Duration(rand.Intn(100)) * time.Millisecond)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(g.scenario.nextDelay(g.rnd)):
+			}
 		}
 	}()
 
-	return eventCh
+	return eventCh, nil
 }
 
-func generate(id int) casino.Event {
-	amount, currency := randomAmountCurrency()
+func (g *generator) generate(id int) casino.Event {
+	event := g.generateValid(id)
+	event.RunID = g.runID
+	if g.faults != nil {
+		g.faults.inject(g.rnd, &event)
+	}
+	return event
+}
 
-	return casino.Event{
+func (g *generator) generateValid(id int) casino.Event {
+	if g.sessions != nil {
+		event := g.sessions.next(g.rnd, id)
+		event.CreatedAt = time.Now()
+		return event
+	}
+
+	if g.pendingWin != nil {
+		event := *g.pendingWin
+		g.pendingWin = nil
+		event.ID = id
+		event.CreatedAt = time.Now()
+		return event
+	}
+
+	event := casino.Event{
 		ID:        id,
-		PlayerID:  10 + rand.Intn(10),
-		GameID:    100 + rand.Intn(10),
-		Type:      randomType(),
-		Amount:    amount,
-		Currency:  currency,
-		HasWon:    randomHasWon(),
+		PlayerID:  g.scenario.randomPlayer(g.rnd),
+		Type:      g.scenario.randomType(g.rnd),
 		CreatedAt: time.Now(),
 	}
-}
 
-func randomType() string {
-	return casino.EventTypes[rand.Intn(len(casino.EventTypes))]
-}
+	// Set only the fields of the event type
+	switch event.Type {
+	case casino.GAME_START, casino.GAME_STOP:
+		event.GameID = g.scenario.randomGame(g.rnd)
+	case casino.BET:
+		event.GameID = g.scenario.randomGame(g.rnd)
+		event.Currency = g.scenario.randomCurrency(g.rnd)
+		event.Amount = g.scenario.randomAmount(g.rnd, event.Currency)
+		event.HasWon = g.scenario.randomHasWon(g.rnd, event.GameID)
+		if event.HasWon {
+			g.pendingWin = newWin(&event)
+		}
+	case casino.DEPOSIT, casino.WITHDRAWAL, casino.BONUS_CREDIT:
+		event.Currency = g.scenario.randomCurrency(g.rnd)
+		event.Amount = g.scenario.randomAmount(g.rnd, event.Currency)
+	}
 
-func randomAmountCurrency() (amount int, currency string) {
-	currency = casino.Currencies[rand.Intn(len(casino.Currencies))]
+	return event
+}
 
-	switch currency {
-	case "BTC":
-		amount = rand.Intn(1e5)
-	default:
-		amount = rand.Intn(2000)
+// Random UUID (version 4) of a generator run
+func newRunID() (string, error) {
+	var b [16]byte
+	if _, err := crand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("generate run ID: %w", err)
 	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
 
-	return
+// Limit the amount to the largest valid amount of the currency
+func clampAmount(amount int, currency string) int {
+	if maxAmount, ok := casino.MaxAmount[currency]; ok && amount > maxAmount {
+		return maxAmount
+	}
+	return amount
 }
 
-func randomHasWon() bool {
-	return rand.Intn(100) < 5
+// Win paying out the won bet
+func newWin(bet *casino.Event) *casino.Event {
+	return &casino.Event{
+		PlayerID: bet.PlayerID,
+		GameID:   bet.GameID,
+		Type:     casino.WIN,
+		Amount:   clampAmount(bet.Amount*WIN_MULTIPLIER, bet.Currency),
+		Currency: bet.Currency,
+		BetID:    bet.ID,
+	}
 }
@@ -0,0 +1,302 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Bitstarz-eng/event-processing-challenge/internal/casino"
+)
+
+// Rate profiles of the load mode
+const (
+	CONSTANT = "constant"
+	RAMP     = "ramp"
+	STEP     = "step"
+	BURST    = "burst"
+)
+
+// Producers do not sleep shorter than this between the batches of events
+const LOAD_TICK = 1 * time.Millisecond
+
+type LoadOptions struct {
+	// One of CONSTANT, RAMP, STEP, BURST
+	Profile string
+
+	// Events per second:
+	// constant - Rate for the whole run
+	// ramp     - linearly from Rate to PeakRate
+	// step     - from Rate to PeakRate in Steps equal steps
+	// burst    - Rate, with PeakRate for BurstLength every BurstEvery
+	Rate        float64
+	PeakRate    float64
+	Steps       int
+	BurstEvery  time.Duration
+	BurstLength time.Duration
+
+	Duration  time.Duration
+	Producers int
+
+	// Size of the buffer between the producers and the publisher, events which do not fit are dropped
+	BufferSize int
+}
+
+func (lo LoadOptions) validate() error {
+	switch lo.Profile {
+	case CONSTANT:
+	case RAMP:
+	case STEP:
+		if lo.Steps < 1 {
+			return fmt.Errorf("step profile needs at least one step")
+		}
+	case BURST:
+		if lo.BurstEvery <= 0 || lo.BurstLength <= 0 || lo.BurstLength > lo.BurstEvery {
+			return fmt.Errorf("burst profile needs a burst length shorter than the burst period")
+		}
+	default:
+		return fmt.Errorf("unknown rate profile %q", lo.Profile)
+	}
+
+	if lo.Rate < 0 || lo.PeakRate < 0 {
+		return fmt.Errorf("rates can not be negative")
+	}
+	if lo.Duration <= 0 {
+		return fmt.Errorf("load duration must be positive")
+	}
+	if lo.Producers < 1 {
+		return fmt.Errorf("at least one producer is required")
+	}
+	if lo.BufferSize < 1 {
+		return fmt.Errorf("buffer size must be at least 1")
+	}
+	return nil
+}
+
+// RateAt returns the target events per second at the elapsed time of the run
+func (lo LoadOptions) RateAt(elapsed time.Duration) float64 {
+	progress := float64(elapsed) / float64(lo.Duration)
+	if progress > 1 {
+		progress = 1
+	}
+
+	switch lo.Profile {
+	case RAMP:
+		return lo.Rate + (lo.PeakRate-lo.Rate)*progress
+	case STEP:
+		if lo.Steps == 1 {
+			return lo.Rate
+		}
+		step := math.Min(math.Floor(progress*float64(lo.Steps)), float64(lo.Steps-1))
+		return lo.Rate + (lo.PeakRate-lo.Rate)*step/float64(lo.Steps-1)
+	case BURST:
+		if elapsed%lo.BurstEvery < lo.BurstLength {
+			return lo.PeakRate
+		}
+		return lo.Rate
+	default:
+		return lo.Rate
+	}
+}
+
+// LoadReport collects the produced, dropped and published events of a load run
+type LoadReport struct {
+	Produced  atomic.Int64
+	Dropped   atomic.Int64
+	Published atomic.Int64
+	Failed    atomic.Int64
+
+	mu        sync.Mutex
+	start     time.Time
+	end       time.Time
+	latencies []time.Duration
+
+	// Generation time of the events waiting to be published. The created_at of the event
+	// is not the generation time, the faults move it.
+	generated map[generatedKey]time.Time
+}
+
+// Identifies a generated event, the duplicate_id fault reuses the IDs
+type generatedKey struct {
+	id        int
+	createdAt int64
+}
+
+func keyOf(event *casino.Event) generatedKey {
+	return generatedKey{id: event.ID, createdAt: event.CreatedAt.UnixNano()}
+}
+
+func newLoadReport() *LoadReport {
+	return &LoadReport{
+		start:     time.Now(),
+		generated: make(map[generatedKey]time.Time),
+	}
+}
+
+// Records the generation time of the event before it is handed to the publisher
+func (lr *LoadReport) recordGenerated(event *casino.Event, at time.Time) {
+	lr.mu.Lock()
+	defer lr.mu.Unlock()
+	lr.generated[keyOf(event)] = at
+}
+
+// Forgets the generation time of a dropped event
+func (lr *LoadReport) recordDropped(event *casino.Event) {
+	lr.Dropped.Add(1)
+
+	lr.mu.Lock()
+	defer lr.mu.Unlock()
+	delete(lr.generated, keyOf(event))
+}
+
+// RecordPublish records the latency from the generation of the event until it was published
+func (lr *LoadReport) RecordPublish(event *casino.Event, err error) {
+	lr.mu.Lock()
+	defer lr.mu.Unlock()
+
+	generatedAt, ok := lr.generated[keyOf(event)]
+	delete(lr.generated, keyOf(event))
+
+	if err != nil {
+		lr.Failed.Add(1)
+		return
+	}
+	lr.Published.Add(1)
+	if ok {
+		lr.latencies = append(lr.latencies, time.Since(generatedAt))
+	}
+	lr.end = time.Now()
+}
+
+func (lr *LoadReport) String() string {
+	lr.mu.Lock()
+	latencies := make([]time.Duration, len(lr.latencies))
+	copy(latencies, lr.latencies)
+	elapsed := lr.end.Sub(lr.start)
+	lr.mu.Unlock()
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	var rate float64
+	if elapsed > 0 {
+		rate = float64(lr.Published.Load()) / elapsed.Seconds()
+	}
+
+	var sb strings.Builder
+	sb.WriteString("Load Report:\n")
+	fmt.Fprintf(&sb, "  produced:      %d\n", lr.Produced.Load())
+	fmt.Fprintf(&sb, "  dropped:       %d\n", lr.Dropped.Load())
+	fmt.Fprintf(&sb, "  published:     %d\n", lr.Published.Load())
+	fmt.Fprintf(&sb, "  failed:        %d\n", lr.Failed.Load())
+	fmt.Fprintf(&sb, "  duration:      %s\n", elapsed.Round(time.Millisecond))
+	fmt.Fprintf(&sb, "  achieved rate: %.2f events/s\n", rate)
+	fmt.Fprintf(&sb, "  latency p50:   %s\n", percentile(latencies, 0.50))
+	fmt.Fprintf(&sb, "  latency p90:   %s\n", percentile(latencies, 0.90))
+	fmt.Fprintf(&sb, "  latency p99:   %s\n", percentile(latencies, 0.99))
+	fmt.Fprintf(&sb, "  latency max:   %s", percentile(latencies, 1))
+	return sb.String()
+}
+
+// Nearest-rank percentile of the sorted latencies
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return sorted[rank].Round(time.Microsecond)
+}
+
+// GenerateLoad runs the producers at the target rate for the load duration.
+// The returned report is complete once the published events are recorded with LoadReport.RecordPublish.
+func GenerateLoad(ctx context.Context, opts Options, load LoadOptions) (<-chan casino.Event, *LoadReport, error) {
+	if err := load.validate(); err != nil {
+		return nil, nil, err
+	}
+
+	generators, recorder, err := newGenerators(opts, load.Producers)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, load.Duration)
+	eventCh := make(chan casino.Event, load.BufferSize)
+	report := newLoadReport()
+	var id atomic.Int64
+	var wg sync.WaitGroup
+
+	for _, g := range generators {
+		wg.Add(1)
+		p := &producer{
+			generator: g,
+			id:        &id,
+			share:     1 / float64(load.Producers),
+		}
+		go func() {
+			defer wg.Done()
+			p.run(ctx, load, eventCh, report, recorder)
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		cancel()
+		close(eventCh)
+		if recorder != nil {
+			recorder.Close()
+		}
+	}()
+
+	return eventCh, report, nil
+}
+
+type producer struct {
+	generator *generator
+
+	// Event IDs are unique across the producers
+	id *atomic.Int64
+
+	// Share of the target rate produced by this producer
+	share float64
+}
+
+func (p *producer) run(ctx context.Context, load LoadOptions, eventCh chan<- casino.Event, report *LoadReport, recorder *Recorder) {
+	start := time.Now()
+	last := start
+	var due float64
+	var sent int
+
+	for {
+		now := time.Now()
+		due += load.RateAt(now.Sub(start)) * p.share * now.Sub(last).Seconds()
+		last = now
+
+		for ; sent < int(due); sent++ {
+			generatedAt := time.Now()
+			event := p.generator.generate(int(p.id.Add(1)))
+			if recorder != nil {
+				recorder.Record(event)
+			}
+
+			report.Produced.Add(1)
+			report.recordGenerated(&event, generatedAt)
+			select {
+			case eventCh <- event:
+			default:
+				report.recordDropped(&event)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(LOAD_TICK):
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package generator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Bitstarz-eng/event-processing-challenge/internal/casino"
+)
+
+func validLoad() LoadOptions {
+	return LoadOptions{
+		Profile:    CONSTANT,
+		Rate:       100,
+		Duration:   time.Second,
+		Producers:  1,
+		BufferSize: 10,
+	}
+}
+
+func TestLoadOptionsValidate(t *testing.T) {
+	for name, change := range map[string]func(*LoadOptions){
+		"unknown profile": func(lo *LoadOptions) { lo.Profile = "sine" },
+		"no steps":        func(lo *LoadOptions) { lo.Profile = STEP },
+		"long burst":      func(lo *LoadOptions) { lo.Profile, lo.BurstEvery, lo.BurstLength = BURST, time.Second, 2*time.Second },
+		"negative rate":   func(lo *LoadOptions) { lo.Rate = -1 },
+		"no duration":     func(lo *LoadOptions) { lo.Duration = 0 },
+		"no producers":    func(lo *LoadOptions) { lo.Producers = 0 },
+		"no buffer":       func(lo *LoadOptions) { lo.BufferSize = 0 },
+		"negative buffer": func(lo *LoadOptions) { lo.BufferSize = -1 },
+	} {
+		load := validLoad()
+		change(&load)
+		if err := load.validate(); err == nil {
+			t.Errorf("%s: options accepted", name)
+		}
+	}
+
+	if err := validLoad().validate(); err != nil {
+		t.Fatalf("valid options rejected: %v", err)
+	}
+}
+
+func TestRateAt(t *testing.T) {
+	load := LoadOptions{Rate: 100, PeakRate: 300, Duration: 4 * time.Second, Steps: 3, BurstEvery: time.Second, BurstLength: 100 * time.Millisecond}
+	for _, tc := range []struct {
+		profile string
+		elapsed time.Duration
+		want    float64
+	}{
+		{CONSTANT, 3 * time.Second, 100},
+		{RAMP, 0, 100},
+		{RAMP, 2 * time.Second, 200},
+		{RAMP, 8 * time.Second, 300},
+		{STEP, time.Second, 100},
+		{STEP, 2 * time.Second, 200},
+		{STEP, 4 * time.Second, 300},
+		{BURST, 1050 * time.Millisecond, 300},
+		{BURST, 1500 * time.Millisecond, 100},
+	} {
+		load.Profile = tc.profile
+		if got := load.RateAt(tc.elapsed); got != tc.want {
+			t.Errorf("%s rate at %s = %v, want %v", tc.profile, tc.elapsed, got, tc.want)
+		}
+	}
+}
+
+func TestRecordPublishMeasuresFromGeneration(t *testing.T) {
+	report := newLoadReport()
+	event := casino.Event{ID: 1, Type: casino.BET, CreatedAt: time.Now().Add(FUTURE_OFFSET), Fault: FAULT_FUTURE_TIMESTAMP}
+	report.recordGenerated(&event, time.Now())
+	report.RecordPublish(&event, nil)
+
+	if len(report.latencies) != 1 || report.latencies[0] < 0 || report.latencies[0] > time.Second {
+		t.Fatalf("latencies = %v, want the time since the generation", report.latencies)
+	}
+	if len(report.generated) != 0 {
+		t.Fatal("generation time of the published event was kept")
+	}
+}
+
+func TestGenerateLoad(t *testing.T) {
+	load := validLoad()
+	load.Rate = 1000
+	load.Duration = 200 * time.Millisecond
+	load.Producers = 2
+	load.BufferSize = 1
+
+	eventCh, report, err := GenerateLoad(context.Background(), Options{Seed: 1}, load)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Nothing reads the full buffer of one event, so the next produced event is dropped
+	deadline := time.Now().Add(5 * time.Second)
+	for report.Dropped.Load() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("no event dropped with a full buffer, produced %d", report.Produced.Load())
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	ids := make(map[int]bool)
+	for event := range eventCh {
+		event := event
+		if ids[event.ID] {
+			t.Fatalf("event ID %d generated twice", event.ID)
+		}
+		ids[event.ID] = true
+		report.RecordPublish(&event, nil)
+	}
+
+	produced, dropped, published := report.Produced.Load(), report.Dropped.Load(), report.Published.Load()
+	if produced == 0 || dropped == 0 || produced != dropped+published {
+		t.Fatalf("produced %d, dropped %d, published %d, want the events over the buffer dropped", produced, dropped, published)
+	}
+	if len(report.latencies) != int(published) || len(report.generated) != 0 {
+		t.Fatalf("%d latencies, %d pending generation times, want %d latencies", len(report.latencies), len(report.generated), published)
+	}
+}
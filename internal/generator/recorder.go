@@ -0,0 +1,56 @@
+package generator
+
+import (
+	"bufio"
+	"encoding/json"
+	"log"
+	"os"
+	"sync"
+
+	"github.com/Bitstarz-eng/event-processing-challenge/internal/casino"
+)
+
+// Recorder writes the generated events to a JSON-lines file, which can be replayed later
+type Recorder struct {
+	mu      sync.Mutex
+	file    *os.File
+	writer  *bufio.Writer
+	encoder *json.Encoder
+}
+
+func NewRecorder(path string) (*Recorder, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	writer := bufio.NewWriter(file)
+	return &Recorder{
+		file:    file,
+		writer:  writer,
+		encoder: json.NewEncoder(writer),
+	}, nil
+}
+
+// Record appends the event as one JSON line
+func (r *Recorder) Record(event casino.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.encoder.Encode(event); err != nil {
+		log.Printf("Failed to record event %d: %v", event.ID, err)
+	}
+}
+
+// Close flushes the buffered events and closes the file
+func (r *Recorder) Close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.writer.Flush(); err != nil {
+		log.Printf("Failed to flush recording: %v", err)
+	}
+	if err := r.file.Close(); err != nil {
+		log.Printf("Failed to close recording: %v", err)
+	}
+}
@@ -0,0 +1,428 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/Bitstarz-eng/event-processing-challenge/internal/casino"
+	"gopkg.in/yaml.v3"
+)
+
+// Amount distribution types
+const (
+	UNIFORM     = "uniform"
+	NORMAL      = "normal"
+	LOGNORMAL   = "lognormal"
+	EXPONENTIAL = "exponential"
+	FIXED       = "fixed"
+)
+
+// Scenario describes the traffic the generator produces, see scenarios/ for examples
+type Scenario struct {
+	// Player population, either weighted IDs or a range of equally likely IDs
+	Players     []WeightedID `json:"players" yaml:"players"`
+	PlayerRange *IDRange     `json:"player_range" yaml:"player_range"`
+
+	// Game popularity
+	Games []WeightedID `json:"games" yaml:"games"`
+
+	// Weights of the event types and the currencies.
+	// Wins can not be weighted, a win follows every won bet.
+	EventTypes map[string]float64 `json:"event_types" yaml:"event_types"`
+	Currencies map[string]float64 `json:"currencies" yaml:"currencies"`
+
+	// Amount distribution per currency, in the smallest unit of the currency (at least 1)
+	Amounts map[string]Distribution `json:"amounts" yaml:"amounts"`
+
+	// Win probability of a bet per game ID, games which are not listed use the default
+	WinProbability        map[int]float64 `json:"win_probability" yaml:"win_probability"`
+	DefaultWinProbability float64         `json:"default_win_probability" yaml:"default_win_probability"`
+
+	// Event rate phases, repeated once the last one ends.
+	// Without phases, the generator sleeps a random 0-100ms between events.
+	RateProfile []RatePhase `json:"rate_profile" yaml:"rate_profile"`
+}
+
+type WeightedID struct {
+	ID     int     `json:"id" yaml:"id"`
+	Weight float64 `json:"weight" yaml:"weight"`
+}
+
+type IDRange struct {
+	From int `json:"from" yaml:"from"`
+	To   int `json:"to" yaml:"to"`
+}
+
+type Distribution struct {
+	Type string `json:"type" yaml:"type"`
+
+	// Bounds of the amount, Max 0 means unbounded (except for uniform)
+	Min float64 `json:"min" yaml:"min"`
+	Max float64 `json:"max" yaml:"max"`
+
+	// Normal: mean and standard deviation, exponential: mean, fixed: the amount
+	Mean   float64 `json:"mean" yaml:"mean"`
+	StdDev float64 `json:"std_dev" yaml:"std_dev"`
+
+	// Lognormal: parameters of the underlying normal distribution
+	Mu    float64 `json:"mu" yaml:"mu"`
+	Sigma float64 `json:"sigma" yaml:"sigma"`
+}
+
+type RatePhase struct {
+	// Duration of the phase, e.g. 30s, 5m
+	Duration string `json:"duration" yaml:"duration"`
+
+	// Events per second at the start of the phase.
+	// If RateTo is set, the rate changes linearly to RateTo until the end of the phase,
+	// so `rate_to: 0` ramps down to idle.
+	Rate   float64  `json:"rate" yaml:"rate"`
+	RateTo *float64 `json:"rate_to" yaml:"rate_to"`
+
+	duration time.Duration
+}
+
+// DefaultScenario generates uniformly distributed players, games, event types and currencies with 5% wins
+func DefaultScenario() *Scenario {
+	scenario := &Scenario{
+		PlayerRange:           &IDRange{From: 10, To: 19},
+		EventTypes:            make(map[string]float64),
+		Currencies:            make(map[string]float64),
+		Amounts:               make(map[string]Distribution),
+		DefaultWinProbability: 0.05,
+	}
+
+	for id := 100; id <= 109; id++ {
+		scenario.Games = append(scenario.Games, WeightedID{ID: id, Weight: 1})
+	}
+	for _, eventType := range casino.EventTypes {
+		if eventType != casino.WIN {
+			scenario.EventTypes[eventType] = 1
+		}
+	}
+	for _, currency := range casino.Currencies {
+		scenario.Currencies[currency] = 1
+		scenario.Amounts[currency] = Distribution{Type: UNIFORM, Min: 0, Max: 2000}
+	}
+	scenario.Amounts["BTC"] = Distribution{Type: UNIFORM, Min: 0, Max: 1e5}
+
+	return scenario
+}
+
+// LoadScenario reads the scenario from a YAML (.yaml, .yml) or JSON file
+func LoadScenario(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unknown keys are rejected, a misspelled key would silently use the default
+	scenario := &Scenario{}
+	switch filepath.Ext(path) {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		err = decoder.Decode(scenario)
+	default:
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(scenario)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse scenario %s: %w", path, err)
+	}
+
+	if err := scenario.validate(); err != nil {
+		return nil, fmt.Errorf("invalid scenario %s: %w", path, err)
+	}
+	return scenario, nil
+}
+
+func (s *Scenario) validate() error {
+	if len(s.Players) == 0 && s.PlayerRange == nil {
+		return fmt.Errorf("players or player_range is required")
+	}
+	if s.PlayerRange != nil && s.PlayerRange.From > s.PlayerRange.To {
+		return fmt.Errorf("player_range from is greater than to")
+	}
+	if len(s.Games) == 0 {
+		return fmt.Errorf("games are required")
+	}
+	if len(s.Players) > 0 {
+		if err := validateIDWeights("players", s.Players); err != nil {
+			return err
+		}
+	}
+	if err := validateIDWeights("games", s.Games); err != nil {
+		return err
+	}
+	for _, game := range s.Games {
+		if _, ok := casino.Games[game.ID]; !ok {
+			return fmt.Errorf("unknown game %d", game.ID)
+		}
+	}
+	if err := validateWeights("event_types", s.EventTypes); err != nil {
+		return err
+	}
+	for eventType := range s.EventTypes {
+		if !contains(casino.EventTypes, eventType) {
+			return fmt.Errorf("unknown event type %q", eventType)
+		}
+	}
+	if _, ok := s.EventTypes[casino.WIN]; ok {
+		return fmt.Errorf("event_types can not have %s, wins follow the won bets", casino.WIN)
+	}
+	if err := validateWeights("currencies", s.Currencies); err != nil {
+		return err
+	}
+	for currency := range s.Currencies {
+		if !contains(casino.Currencies, currency) {
+			return fmt.Errorf("unknown currency %q", currency)
+		}
+	}
+
+	for currency := range s.Currencies {
+		distribution, ok := s.Amounts[currency]
+		if !ok {
+			return fmt.Errorf("amount distribution of %s is missing", currency)
+		}
+		switch distribution.Type {
+		case UNIFORM:
+			if distribution.Max <= distribution.Min {
+				return fmt.Errorf("uniform amount of %s needs max greater than min", currency)
+			}
+		case NORMAL, LOGNORMAL, EXPONENTIAL, FIXED:
+		default:
+			return fmt.Errorf("unknown amount distribution %q of %s", distribution.Type, currency)
+		}
+	}
+
+	for gameID, probability := range s.WinProbability {
+		if _, ok := casino.Games[gameID]; !ok {
+			return fmt.Errorf("win probability of unknown game %d", gameID)
+		}
+		if probability < 0 || probability > 1 {
+			return fmt.Errorf("win probability of game %d must be between 0 and 1", gameID)
+		}
+	}
+	if s.DefaultWinProbability < 0 || s.DefaultWinProbability > 1 {
+		return fmt.Errorf("default win probability must be between 0 and 1")
+	}
+
+	for i := range s.RateProfile {
+		phase := &s.RateProfile[i]
+		duration, err := time.ParseDuration(phase.Duration)
+		if err != nil || duration <= 0 {
+			return fmt.Errorf("rate phase %d needs a positive duration", i+1)
+		}
+		if phase.Rate < 0 || (phase.RateTo != nil && *phase.RateTo < 0) {
+			return fmt.Errorf("rate phase %d has a negative rate", i+1)
+		}
+		phase.duration = duration
+	}
+
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func validateWeights(name string, weights map[string]float64) error {
+	var total float64
+	for _, weight := range weights {
+		if weight < 0 {
+			return fmt.Errorf("%s can not have negative weights", name)
+		}
+		total += weight
+	}
+	if total <= 0 {
+		return fmt.Errorf("%s need at least one positive weight", name)
+	}
+	return nil
+}
+
+func validateIDWeights(name string, ids []WeightedID) error {
+	weights := make(map[string]float64, len(ids))
+	for _, id := range ids {
+		weights[fmt.Sprint(id.ID)] += id.Weight
+	}
+	return validateWeights(name, weights)
+}
+
+// weighted picks values by weight, the values are sorted so the same seed picks the same values
+type weighted struct {
+	values     []string
+	cumulative []float64
+}
+
+func newWeighted(weights map[string]float64) *weighted {
+	values := make([]string, 0, len(weights))
+	for value := range weights {
+		values = append(values, value)
+	}
+	sort.Strings(values)
+
+	w := &weighted{values: values}
+	var total float64
+	for _, value := range values {
+		total += weights[value]
+		w.cumulative = append(w.cumulative, total)
+	}
+	return w
+}
+
+func (w *weighted) pick(rnd *rand.Rand) string {
+	r := rnd.Float64() * w.cumulative[len(w.cumulative)-1]
+	return w.values[sort.SearchFloat64s(w.cumulative, r)]
+}
+
+type weightedIDs struct {
+	ids        []int
+	cumulative []float64
+}
+
+func newWeightedIDs(ids []WeightedID) *weightedIDs {
+	w := &weightedIDs{}
+	var total float64
+	for _, id := range ids {
+		total += id.Weight
+		w.ids = append(w.ids, id.ID)
+		w.cumulative = append(w.cumulative, total)
+	}
+	return w
+}
+
+func (w *weightedIDs) pick(rnd *rand.Rand) int {
+	r := rnd.Float64() * w.cumulative[len(w.cumulative)-1]
+	return w.ids[sort.SearchFloat64s(w.cumulative, r)]
+}
+
+// scenarioRunner draws the event fields from the scenario
+type scenarioRunner struct {
+	scenario   *Scenario
+	players    *weightedIDs
+	games      *weightedIDs
+	eventTypes *weighted
+	currencies *weighted
+	start      time.Time
+}
+
+func newScenarioRunner(scenario *Scenario) *scenarioRunner {
+	players := scenario.Players
+	if len(players) == 0 {
+		for id := scenario.PlayerRange.From; id <= scenario.PlayerRange.To; id++ {
+			players = append(players, WeightedID{ID: id, Weight: 1})
+		}
+	}
+
+	return &scenarioRunner{
+		scenario:   scenario,
+		players:    newWeightedIDs(players),
+		games:      newWeightedIDs(scenario.Games),
+		eventTypes: newWeighted(scenario.EventTypes),
+		currencies: newWeighted(scenario.Currencies),
+		start:      time.Now(),
+	}
+}
+
+func (sr *scenarioRunner) randomPlayer(rnd *rand.Rand) int {
+	return sr.players.pick(rnd)
+}
+
+func (sr *scenarioRunner) randomGame(rnd *rand.Rand) int {
+	return sr.games.pick(rnd)
+}
+
+func (sr *scenarioRunner) randomType(rnd *rand.Rand) string {
+	return sr.eventTypes.pick(rnd)
+}
+
+func (sr *scenarioRunner) randomCurrency(rnd *rand.Rand) string {
+	return sr.currencies.pick(rnd)
+}
+
+func (sr *scenarioRunner) randomAmount(rnd *rand.Rand, currency string) int {
+	d := sr.scenario.Amounts[currency]
+
+	var amount float64
+	switch d.Type {
+	case UNIFORM:
+		amount = d.Min + rnd.Float64()*(d.Max-d.Min)
+	case NORMAL:
+		amount = d.Mean + rnd.NormFloat64()*d.StdDev
+	case LOGNORMAL:
+		amount = math.Exp(d.Mu + rnd.NormFloat64()*d.Sigma)
+	case EXPONENTIAL:
+		amount = rnd.ExpFloat64() * d.Mean
+	case FIXED:
+		amount = d.Mean
+	}
+
+	if amount < d.Min {
+		amount = d.Min
+	}
+	if d.Max > 0 && amount > d.Max {
+		amount = d.Max
+	}
+	// Amounts are at least one smallest unit
+	if amount < 1 {
+		amount = 1
+	}
+	return clampAmount(int(amount), currency)
+}
+
+func (sr *scenarioRunner) randomHasWon(rnd *rand.Rand, gameID int) bool {
+	probability, ok := sr.scenario.WinProbability[gameID]
+	if !ok {
+		probability = sr.scenario.DefaultWinProbability
+	}
+	return rnd.Float64() < probability
+}
+
+// Delay before the next event. With a rate profile, the events arrive as a Poisson process with the current rate.
+func (sr *scenarioRunner) nextDelay(rnd *rand.Rand) time.Duration {
+	if len(sr.scenario.RateProfile) == 0 {
+		return time.Duration(rnd.Intn(100)) * time.Millisecond
+	}
+
+	rate := sr.currentRate(time.Since(sr.start))
+	if rate <= 0 {
+		// Idle phase, check again shortly
+		return 100 * time.Millisecond
+	}
+	return time.Duration(rnd.ExpFloat64() / rate * float64(time.Second))
+}
+
+// Rate of the phase at the elapsed time, the profile repeats after the last phase
+func (sr *scenarioRunner) currentRate(elapsed time.Duration) float64 {
+	var cycle time.Duration
+	for _, phase := range sr.scenario.RateProfile {
+		cycle += phase.duration
+	}
+	elapsed %= cycle
+
+	for _, phase := range sr.scenario.RateProfile {
+		if elapsed < phase.duration {
+			if phase.RateTo == nil {
+				return phase.Rate
+			}
+			progress := float64(elapsed) / float64(phase.duration)
+			return phase.Rate + (*phase.RateTo-phase.Rate)*progress
+		}
+		elapsed -= phase.duration
+	}
+	return 0
+}
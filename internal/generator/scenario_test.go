@@ -0,0 +1,141 @@
+package generator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Bitstarz-eng/event-processing-challenge/internal/casino"
+)
+
+func TestLoadScenarioExamples(t *testing.T) {
+	for _, name := range []string{"evening_peak.yaml", "uniform.json"} {
+		scenario, err := LoadScenario(filepath.Join("..", "..", "scenarios", name))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(scenario.Games) == 0 || len(scenario.RateProfile) == 0 || scenario.RateProfile[0].duration <= 0 {
+			t.Fatalf("%s parsed to %+v", name, scenario)
+		}
+	}
+}
+
+func TestLoadScenarioRejectsInvalidScenarios(t *testing.T) {
+	for name, scenario := range map[string]string{
+		"no players":             `{"games": [{"id": 100, "weight": 1}], "event_types": {"bet": 1}, "currencies": {"EUR": 1}, "amounts": {"EUR": {"type": "fixed", "mean": 100}}}`,
+		"unknown game":           `{"player_range": {"from": 1, "to": 2}, "games": [{"id": 999, "weight": 1}], "event_types": {"bet": 1}, "currencies": {"EUR": 1}, "amounts": {"EUR": {"type": "fixed", "mean": 100}}}`,
+		"unknown event type":     `{"player_range": {"from": 1, "to": 2}, "games": [{"id": 100, "weight": 1}], "event_types": {"jackpot_spin": 1}, "currencies": {"EUR": 1}, "amounts": {"EUR": {"type": "fixed", "mean": 100}}}`,
+		"weighted wins":          `{"player_range": {"from": 1, "to": 2}, "games": [{"id": 100, "weight": 1}], "event_types": {"bet": 1, "win": 1}, "currencies": {"EUR": 1}, "amounts": {"EUR": {"type": "fixed", "mean": 100}}}`,
+		"unknown currency":       `{"player_range": {"from": 1, "to": 2}, "games": [{"id": 100, "weight": 1}], "event_types": {"bet": 1}, "currencies": {"XYZ": 1}, "amounts": {"XYZ": {"type": "fixed", "mean": 100}}}`,
+		"missing amount":         `{"player_range": {"from": 1, "to": 2}, "games": [{"id": 100, "weight": 1}], "event_types": {"bet": 1}, "currencies": {"EUR": 1}}`,
+		"unknown win game":       `{"player_range": {"from": 1, "to": 2}, "games": [{"id": 100, "weight": 1}], "event_types": {"bet": 1}, "currencies": {"EUR": 1}, "amounts": {"EUR": {"type": "fixed", "mean": 100}}, "win_probability": {"999": 0.5}}`,
+		"win probability":        `{"player_range": {"from": 1, "to": 2}, "games": [{"id": 100, "weight": 1}], "event_types": {"bet": 1}, "currencies": {"EUR": 1}, "amounts": {"EUR": {"type": "fixed", "mean": 100}}, "win_probability": {"100": 1.5}}`,
+		"default probability":    `{"player_range": {"from": 1, "to": 2}, "games": [{"id": 100, "weight": 1}], "event_types": {"bet": 1}, "currencies": {"EUR": 1}, "amounts": {"EUR": {"type": "fixed", "mean": 100}}, "default_win_probability": -0.1}`,
+		"negative rate_to":       `{"player_range": {"from": 1, "to": 2}, "games": [{"id": 100, "weight": 1}], "event_types": {"bet": 1}, "currencies": {"EUR": 1}, "amounts": {"EUR": {"type": "fixed", "mean": 100}}, "rate_profile": [{"duration": "1s", "rate": 1, "rate_to": -1}]}`,
+		"phase without duration": `{"player_range": {"from": 1, "to": 2}, "games": [{"id": 100, "weight": 1}], "event_types": {"bet": 1}, "currencies": {"EUR": 1}, "amounts": {"EUR": {"type": "fixed", "mean": 100}}, "rate_profile": [{"rate": 1}]}`,
+	} {
+		path := filepath.Join(t.TempDir(), "scenario.json")
+		if err := os.WriteFile(path, []byte(scenario), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadScenario(path); err == nil {
+			t.Errorf("%s: scenario accepted", name)
+		}
+	}
+}
+
+func TestLoadScenarioRejectsUnknownKeys(t *testing.T) {
+	for name, scenario := range map[string]string{
+		"scenario.json": `{"player_range": {"from": 1, "to": 2}, "games": [{"id": 100, "weight": 1}], "event_types": {"bet": 1}, "currencies": {"EUR": 1}, "amounts": {"EUR": {"type": "fixed", "mean": 100}}, "win_probabilty": {"100": 0.5}}`,
+		"scenario.yaml": `
+player_range: { from: 1, to: 2 }
+games: [{ id: 100, weight: 1 }]
+event_types: { bet: 1 }
+currencies: { EUR: 1 }
+amounts: { EUR: { type: fixed, mean: 100 } }
+win_probabilty: { 100: 0.5 }
+`,
+	} {
+		path := filepath.Join(t.TempDir(), name)
+		if err := os.WriteFile(path, []byte(scenario), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadScenario(path); err == nil || !strings.Contains(err.Error(), "win_probabilty") {
+			t.Errorf("%s with a misspelled key: error %v", name, err)
+		}
+	}
+}
+
+func TestRateProfileRampsDownToZero(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scenario.yaml")
+	scenario := `
+player_range: { from: 1, to: 2 }
+games: [{ id: 100, weight: 1 }]
+event_types: { bet: 1 }
+currencies: { EUR: 1 }
+amounts: { EUR: { type: fixed, mean: 100 } }
+rate_profile:
+  - { duration: 10s, rate: 100, rate_to: 0 }
+  - { duration: 10s, rate: 50 }
+`
+	if err := os.WriteFile(path, []byte(scenario), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadScenario(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	runner := newScenarioRunner(loaded)
+	for _, tc := range []struct {
+		elapsed time.Duration
+		want    float64
+	}{
+		{0, 100},
+		{5 * time.Second, 50},
+		{15 * time.Second, 50},
+		{25 * time.Second, 50},
+	} {
+		if got := runner.currentRate(tc.elapsed); got != tc.want {
+			t.Errorf("rate at %s = %v, want %v", tc.elapsed, got, tc.want)
+		}
+	}
+}
+
+func TestDefaultScenarioIsValid(t *testing.T) {
+	scenario := DefaultScenario()
+	if err := scenario.validate(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := scenario.EventTypes[casino.WIN]; ok {
+		t.Fatal("default scenario weights the wins")
+	}
+}
+
+func TestGenerateValidatesTheScenario(t *testing.T) {
+	scenario := DefaultScenario()
+	// The phase durations are parsed by the validation, unvalidated phases have no duration
+	scenario.RateProfile = []RatePhase{{Duration: "1s", Rate: 10}}
+	scenario.Games = append(scenario.Games, WeightedID{ID: 999, Weight: 1})
+	if _, err := Generate(context.Background(), Options{Seed: 1, Scenario: scenario}); err == nil {
+		t.Fatal("invalid scenario accepted by Generate")
+	}
+	if _, _, err := GenerateLoad(context.Background(), Options{Seed: 1, Scenario: scenario}, validLoad()); err == nil {
+		t.Fatal("invalid scenario accepted by GenerateLoad")
+	}
+
+	scenario = DefaultScenario()
+	scenario.RateProfile = []RatePhase{{Duration: "1s", Rate: 10}}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	eventCh, err := Generate(ctx, Options{Seed: 1, Scenario: scenario})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := <-eventCh; !ok {
+		t.Fatal("no event generated with the rate profile")
+	}
+}
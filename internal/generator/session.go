@@ -0,0 +1,187 @@
+package generator
+
+import (
+	"math/rand"
+	"sync"
+
+	"github.com/Bitstarz-eng/event-processing-challenge/internal/casino"
+)
+
+// States of a simulated player
+const (
+	LOGGED_OUT = iota
+	LOGGED_IN
+	IN_LOBBY
+	PLAYING
+	LOGGING_OUT
+)
+
+const (
+	// A deposit covers about this many bets
+	DEPOSIT_TO_BET_RATIO = 10
+
+	// Max number of bets in one game session
+	MAX_SESSION_BETS = 20
+
+	// A winning bet pays back this multiple of the stake
+	WIN_MULTIPLIER = 2
+
+	// Percentage of the logins with a bonus credit, the bonus is worth about one bet
+	BONUS_PERCENT = 10
+
+	// Percentage of the logouts with a positive balance which withdraw the balance
+	WITHDRAWAL_PERCENT = 50
+)
+
+type simulatedPlayer struct {
+	id       int
+	state    int
+	currency string
+	balance  int // Smallest unit of the currency
+	gameID   int
+	betsLeft int
+
+	// Won bet, paid out with the next event of the player
+	pendingWin *casino.Event
+}
+
+// sessionSimulator moves every player through login -> deposit -> game_start -> bets -> game_stop,
+// so the generated events are consistent per player: bets only on started games, never more than the balance.
+// The players are shared by all generators, so the simulator is safe for concurrent use.
+type sessionSimulator struct {
+	mu       sync.Mutex
+	scenario *scenarioRunner
+	players  map[int]*simulatedPlayer
+}
+
+func newSessionSimulator(scenario *scenarioRunner) *sessionSimulator {
+	return &sessionSimulator{
+		scenario: scenario,
+		players:  make(map[int]*simulatedPlayer),
+	}
+}
+
+// Advance a random player to its next event
+func (ss *sessionSimulator) next(rnd *rand.Rand, id int) casino.Event {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	playerID := ss.scenario.randomPlayer(rnd)
+	player, ok := ss.players[playerID]
+	if !ok {
+		player = &simulatedPlayer{id: playerID}
+		ss.players[playerID] = player
+	}
+
+	for {
+		if event, ok := ss.step(rnd, player); ok {
+			event.ID = id
+			if event.Type == casino.BET && event.HasWon {
+				player.pendingWin = newWin(&event)
+			}
+			return event
+		}
+	}
+}
+
+// Move the player to the next state, returns false if the transition has no event
+func (ss *sessionSimulator) step(rnd *rand.Rand, player *simulatedPlayer) (casino.Event, bool) {
+	event := casino.Event{PlayerID: player.id}
+
+	switch player.state {
+	case LOGGED_OUT:
+		// The balance stays in the currency of the previous deposits
+		if player.balance == 0 {
+			player.currency = ss.scenario.randomCurrency(rnd)
+		}
+		player.state = LOGGED_IN
+
+		if rnd.Intn(100) >= BONUS_PERCENT {
+			return event, false
+		}
+		amount := ss.scenario.randomAmount(rnd, player.currency)
+		player.balance += amount
+
+		event.Type = casino.BONUS_CREDIT
+		event.Amount = amount
+		event.Currency = player.currency
+		return event, true
+
+	case LOGGED_IN:
+		amount := clampAmount(ss.scenario.randomAmount(rnd, player.currency)*DEPOSIT_TO_BET_RATIO, player.currency)
+		player.balance += amount
+		player.state = IN_LOBBY
+
+		event.Type = casino.DEPOSIT
+		event.Amount = amount
+		event.Currency = player.currency
+		return event, true
+
+	case IN_LOBBY:
+		player.gameID = ss.scenario.randomGame(rnd)
+		player.betsLeft = 1 + rnd.Intn(MAX_SESSION_BETS)
+		player.state = PLAYING
+
+		event.Type = casino.GAME_START
+		event.GameID = player.gameID
+		return event, true
+
+	case PLAYING:
+		if player.pendingWin != nil {
+			event = *player.pendingWin
+			player.pendingWin = nil
+			player.balance += event.Amount
+			return event, true
+		}
+
+		stake := ss.scenario.randomAmount(rnd, player.currency)
+		if stake > player.balance {
+			stake = player.balance
+		}
+
+		if player.betsLeft == 0 || stake <= 0 {
+			ss.stopGame(rnd, player)
+
+			event.Type = casino.GAME_STOP
+			event.GameID = player.gameID
+			return event, true
+		}
+
+		player.betsLeft--
+		player.balance -= stake
+
+		event.Type = casino.BET
+		event.GameID = player.gameID
+		event.Amount = stake
+		event.Currency = player.currency
+		event.HasWon = ss.scenario.randomHasWon(rnd, player.gameID)
+		return event, true
+
+	case LOGGING_OUT:
+		player.state = LOGGED_OUT
+		if player.balance == 0 || rnd.Intn(100) >= WITHDRAWAL_PERCENT {
+			return event, false
+		}
+
+		// A balance above the largest amount is withdrawn over several logouts
+		event.Type = casino.WITHDRAWAL
+		event.Amount = clampAmount(player.balance, player.currency)
+		event.Currency = player.currency
+		player.balance -= event.Amount
+		return event, true
+	}
+
+	return event, false
+}
+
+// After a game the player deposits again, plays another game or logs out
+func (ss *sessionSimulator) stopGame(rnd *rand.Rand, player *simulatedPlayer) {
+	switch {
+	case player.balance == 0 && rnd.Intn(2) == 0:
+		player.state = LOGGED_IN
+	case player.balance > 0 && rnd.Intn(10) < 6:
+		player.state = IN_LOBBY
+	default:
+		player.state = LOGGING_OUT
+	}
+}
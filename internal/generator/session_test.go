@@ -0,0 +1,38 @@
+package generator
+
+import (
+	"testing"
+
+	"github.com/Bitstarz-eng/event-processing-challenge/internal/casino"
+)
+
+func TestSessionAmountsStayValid(t *testing.T) {
+	// Stakes of the largest amount, so the deposits, wins and withdrawals would exceed it
+	maxAmount := float64(casino.MaxAmount["EUR"])
+	scenario := &Scenario{
+		PlayerRange:           &IDRange{From: 10, To: 12},
+		Games:                 []WeightedID{{ID: 100, Weight: 1}},
+		EventTypes:            map[string]float64{casino.BET: 1},
+		Currencies:            map[string]float64{"EUR": 1},
+		Amounts:               map[string]Distribution{"EUR": {Type: FIXED, Mean: maxAmount}},
+		DefaultWinProbability: 1,
+	}
+
+	generators, _, err := newGenerators(Options{Seed: 1, Scenario: scenario, Sessions: true}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	types := make(map[string]bool)
+	for id := 1; id <= 1000; id++ {
+		event := generators[0].generate(id)
+		if err := event.Validate(); err != nil {
+			t.Fatalf("generated %s %d: %v", event.Type, event.Amount, err)
+		}
+		types[event.Type] = true
+	}
+	for _, eventType := range []string{casino.DEPOSIT, casino.BET, casino.WIN, casino.WITHDRAWAL} {
+		if !types[eventType] {
+			t.Fatalf("no %s generated", eventType)
+		}
+	}
+}
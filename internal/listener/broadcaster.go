@@ -0,0 +1,185 @@
+package listener
+
+import (
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Pushes buffered for a WebSocket client, a client which falls further behind is disconnected
+const WS_CLIENT_BUFFER_SIZE = 32
+
+// Stats group in a window, the unit of the pushes
+type wsKey struct {
+	group  string
+	window string
+}
+
+// wsClient receives the pushes of its subscribed groups, the channel is closed when the client is dropped
+type wsClient struct {
+	messages chan *websocket.PreparedMessage
+	keys     map[wsKey]bool
+}
+
+// statsBroadcaster builds every subscribed group once per push interval for all WebSocket clients,
+// and pushes the same encoded message to the subscribed clients when the group changed
+type statsBroadcaster struct {
+	build    func(group, window string) interface{}
+	interval time.Duration
+
+	mu      sync.Mutex
+	clients map[*wsClient]bool
+	// Last pushed data and message of each subscribed group
+	data     map[wsKey][]byte
+	messages map[wsKey]*websocket.PreparedMessage
+}
+
+func newStatsBroadcaster(build func(group, window string) interface{}, interval time.Duration) *statsBroadcaster {
+	return &statsBroadcaster{
+		build:    build,
+		interval: interval,
+		clients:  make(map[*wsClient]bool),
+		data:     make(map[wsKey][]byte),
+		messages: make(map[wsKey]*websocket.PreparedMessage),
+	}
+}
+
+// Pushes the changed groups on every interval until done is closed
+func (b *statsBroadcaster) run(done <-chan struct{}) {
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			b.push()
+		case <-done:
+			return
+		}
+	}
+}
+
+func (b *statsBroadcaster) register() *wsClient {
+	client := &wsClient{
+		messages: make(chan *websocket.PreparedMessage, WS_CLIENT_BUFFER_SIZE),
+		keys:     make(map[wsKey]bool),
+	}
+
+	b.mu.Lock()
+	b.clients[client] = true
+	b.mu.Unlock()
+	return client
+}
+
+func (b *statsBroadcaster) unregister(client *wsClient) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.drop(client)
+}
+
+// Replaces the subscribed groups of the client. The last message of a newly subscribed group is pushed right away,
+// a group nobody subscribed before is pushed on the next interval.
+func (b *statsBroadcaster) subscribe(client *wsClient, groups map[string]bool, window string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.clients[client] {
+		return
+	}
+
+	keys := make(map[wsKey]bool, len(groups))
+	for group := range groups {
+		key := wsKey{group: group, window: window}
+		keys[key] = true
+		if message, ok := b.messages[key]; ok && !client.keys[key] {
+			b.send(client, message)
+		}
+	}
+	client.keys = keys
+	b.forgetUnsubscribed()
+}
+
+// Builds the subscribed groups and pushes the changed ones
+func (b *statsBroadcaster) push() {
+	b.mu.Lock()
+	keys := make(map[wsKey]bool)
+	for client := range b.clients {
+		for key := range client.keys {
+			keys[key] = true
+		}
+	}
+	b.mu.Unlock()
+
+	// The groups are built without the lock, so the clients can subscribe meanwhile
+	built := make(map[wsKey][]byte, len(keys))
+	for key := range keys {
+		data, err := json.Marshal(b.build(key.group, key.window))
+		if err != nil {
+			log.Printf("Failed to marshal the %s stats: %v", key.group, err)
+			continue
+		}
+		built[key] = data
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for key, data := range built {
+		if last, ok := b.data[key]; ok && string(last) == string(data) {
+			continue
+		}
+
+		encoded, err := json.Marshal(wsMessage{Group: key.group, Window: key.window, Data: data})
+		if err != nil {
+			log.Printf("Failed to marshal the %s message: %v", key.group, err)
+			continue
+		}
+		message, err := websocket.NewPreparedMessage(websocket.TextMessage, encoded)
+		if err != nil {
+			log.Printf("Failed to prepare the %s message: %v", key.group, err)
+			continue
+		}
+		b.data[key] = data
+		b.messages[key] = message
+
+		for client := range b.clients {
+			if client.keys[key] {
+				b.send(client, message)
+			}
+		}
+	}
+	b.forgetUnsubscribed()
+}
+
+// Sends the message without blocking, a client with a full buffer is dropped
+func (b *statsBroadcaster) send(client *wsClient, message *websocket.PreparedMessage) {
+	select {
+	case client.messages <- message:
+	default:
+		b.drop(client)
+	}
+}
+
+func (b *statsBroadcaster) drop(client *wsClient) {
+	if b.clients[client] {
+		delete(b.clients, client)
+		close(client.messages)
+	}
+}
+
+// Forgets the last messages of the groups without clients, so a new client never gets an outdated message
+func (b *statsBroadcaster) forgetUnsubscribed() {
+	subscribed := make(map[wsKey]bool)
+	for client := range b.clients {
+		for key := range client.keys {
+			subscribed[key] = true
+		}
+	}
+	for key := range b.data {
+		if !subscribed[key] {
+			delete(b.data, key)
+			delete(b.messages, key)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package listener
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func received(client *wsClient) []*websocket.PreparedMessage {
+	var messages []*websocket.PreparedMessage
+	for {
+		select {
+		case message, ok := <-client.messages:
+			if !ok {
+				return messages
+			}
+			messages = append(messages, message)
+		default:
+			return messages
+		}
+	}
+}
+
+func TestBroadcasterBuildsOncePerInterval(t *testing.T) {
+	builds := 0
+	value := 1
+	b := newStatsBroadcaster(func(group, window string) interface{} {
+		builds++
+		return value
+	}, time.Second)
+
+	first, second := b.register(), b.register()
+	groups := map[string]bool{WS_GROUP_SESSIONS: true}
+	b.subscribe(first, groups, "all")
+	b.subscribe(second, groups, "all")
+
+	b.push()
+	firstMessages, secondMessages := received(first), received(second)
+	if builds != 1 || len(firstMessages) != 1 || len(secondMessages) != 1 || firstMessages[0] != secondMessages[0] {
+		t.Fatalf("builds = %d, messages = %d/%d, want one build and the same message for both clients", builds, len(firstMessages), len(secondMessages))
+	}
+
+	b.push()
+	if messages := received(first); builds != 2 || len(messages) != 0 {
+		t.Fatalf("builds = %d, messages = %d after an unchanged push, want 2 builds and no message", builds, len(messages))
+	}
+
+	// A new client gets the last message right away
+	third := b.register()
+	b.subscribe(third, groups, "all")
+	if messages := received(third); len(messages) != 1 || messages[0] != firstMessages[0] {
+		t.Fatalf("new client got %d messages, want the last message", len(messages))
+	}
+
+	value = 2
+	b.push()
+	if len(received(first)) != 1 || len(received(third)) != 1 {
+		t.Fatal("changed group was not pushed to every client")
+	}
+}
+
+func TestBroadcasterDropsSlowClients(t *testing.T) {
+	value := 0
+	b := newStatsBroadcaster(func(group, window string) interface{} {
+		value++
+		return value
+	}, time.Second)
+
+	client := b.register()
+	b.subscribe(client, map[string]bool{WS_GROUP_SESSIONS: true}, "all")
+	for i := 0; i <= WS_CLIENT_BUFFER_SIZE; i++ {
+		b.push()
+	}
+
+	if messages := received(client); len(messages) != WS_CLIENT_BUFFER_SIZE {
+		t.Fatalf("slow client received %d messages, want %d", len(messages), WS_CLIENT_BUFFER_SIZE)
+	}
+	if _, ok := <-client.messages; ok || len(b.clients) != 0 {
+		t.Fatal("slow client was not dropped")
+	}
+}
@@ -0,0 +1,89 @@
+package listener
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Bitstarz-eng/event-processing-challenge/internal/casino"
+	"github.com/Bitstarz-eng/event-processing-challenge/internal/statistics"
+)
+
+// Serves the amount distributions of all metrics on /distributions and of one metric on /distributions/{metric},
+// filtered by ?game={id} or ?currency={currency}
+func (m *Materialized) distributionsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	metric := strings.Trim(strings.TrimPrefix(r.URL.Path, "/distributions"), "/")
+	if metric == "" {
+		distributions := make(map[string]*statistics.DistributionStats, len(statistics.DistributionMetrics))
+		for _, metric := range statistics.DistributionMetrics {
+			distributions[metric], _ = m.Publisher.GetDistribution(metric)
+		}
+		writeJSON(w, distributions)
+		return
+	}
+
+	stats, ok := m.Publisher.GetDistribution(metric)
+	if !ok {
+		http.Error(w, "Unknown distribution metric", http.StatusNotFound)
+		return
+	}
+
+	game := r.URL.Query().Get("game")
+	currency := r.URL.Query().Get("currency")
+	switch {
+	case game != "" && currency != "":
+		http.Error(w, "Only one of game and currency can be set", http.StatusBadRequest)
+	case game != "":
+		gameId, err := strconv.Atoi(game)
+		if err != nil {
+			http.Error(w, "Invalid game ID", http.StatusBadRequest)
+			return
+		}
+		if _, ok := casino.Games[gameId]; !ok {
+			http.Error(w, "Game not found", http.StatusNotFound)
+			return
+		}
+		summary, ok := stats.PerGame[gameId]
+		if !ok {
+			summary = statistics.NewDistribution().Summary()
+		}
+		writeJSON(w, map[string]interface{}{
+			"metric":       metric,
+			"game":         gameId,
+			"distribution": summary,
+		})
+	case currency != "":
+		if !isCurrency(currency) {
+			http.Error(w, "Unknown currency", http.StatusNotFound)
+			return
+		}
+		summary, ok := stats.PerCurrency[currency]
+		if !ok {
+			summary = statistics.NewDistribution().Summary()
+		}
+		writeJSON(w, map[string]interface{}{
+			"metric":       metric,
+			"currency":     currency,
+			"distribution": summary,
+		})
+	default:
+		writeJSON(w, map[string]interface{}{
+			"metric":       metric,
+			"distribution": stats,
+		})
+	}
+}
+
+func isCurrency(currency string) bool {
+	for _, c := range casino.Currencies {
+		if c == currency {
+			return true
+		}
+	}
+	return false
+}
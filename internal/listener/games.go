@@ -0,0 +1,56 @@
+package listener
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Serves the game part of /materialized: most played game, most betted game and the statistics of each game
+func (m *Materialized) materializedGamesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, m.Publisher.GetGameStats())
+}
+
+// Serves the statistics of all games on /games and of one game on /games/{id}
+func (m *Materialized) gamesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/games"), "/")
+	if path == "" {
+		writeJSON(w, m.Publisher.GetGames())
+		return
+	}
+
+	gameId, err := strconv.Atoi(path)
+	if err != nil {
+		http.Error(w, "Invalid game ID", http.StatusBadRequest)
+		return
+	}
+
+	game, ok := m.Publisher.GetGame(gameId)
+	if !ok {
+		http.Error(w, "Game not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, game)
+}
+
+func writeJSON(w http.ResponseWriter, value interface{}) {
+	response, err := json.Marshal(value)
+	if err != nil {
+		http.Error(w, "Failed to marshal JSON", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(response)
+}
@@ -0,0 +1,50 @@
+package listener
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Bitstarz-eng/event-processing-challenge/internal/statistics"
+)
+
+func TestGamesEndpoints(t *testing.T) {
+	m := newTestMaterialized(t)
+
+	recorder := httptest.NewRecorder()
+	m.gamesHandler(recorder, httptest.NewRequest(http.MethodGet, "/games", nil))
+	var games []statistics.GameSummary
+	if err := json.Unmarshal(recorder.Body.Bytes(), &games); err != nil {
+		t.Fatal(err)
+	}
+	if len(games) != 1 || games[0].Id != 100 || games[0].GGR != -12.5 {
+		t.Fatalf("/games = %+v, want game 100 with a GGR of -12.5", games)
+	}
+
+	game := getJSON(t, m.gamesHandler, "/games/100")
+	for field, want := range map[string]float64{
+		"bet_count":         1,
+		"stakes_eur":        12.5,
+		"payouts_eur":       25,
+		"ggr_eur":           -12.5,
+		"rtp":               2,
+		"average_stake_eur": 12.5,
+		"unique_players":    1,
+	} {
+		if game[field] != want {
+			t.Errorf("/games/100 %s = %v, want %v", field, game[field], want)
+		}
+	}
+
+	for target, code := range map[string]int{
+		"/games/999":  http.StatusNotFound,
+		"/games/slot": http.StatusBadRequest,
+	} {
+		recorder := httptest.NewRecorder()
+		m.gamesHandler(recorder, httptest.NewRequest(http.MethodGet, target, nil))
+		if recorder.Code != code {
+			t.Errorf("GET %s = %d, want %d", target, recorder.Code, code)
+		}
+	}
+}
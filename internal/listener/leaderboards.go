@@ -0,0 +1,67 @@
+package listener
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Bitstarz-eng/event-processing-challenge/internal/statistics"
+)
+
+// Serves the leaderboard of one metric on /leaderboards/{metric}?limit=N&window=W and of all metrics on /leaderboards
+func (m *Materialized) leaderboardsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	window, ok := parseWindow(w, r)
+	if !ok {
+		return
+	}
+
+	limit := m.LeaderboardSize
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 || parsed > m.LeaderboardSize {
+			http.Error(w, fmt.Sprintf("Invalid limit, must be between 1 and %d", m.LeaderboardSize), http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	metric := strings.Trim(strings.TrimPrefix(r.URL.Path, "/leaderboards"), "/")
+	if metric == "" {
+		leaderboards := make(map[string][]statistics.LeaderboardEntry, len(statistics.LeaderboardMetrics))
+		for _, metric := range statistics.LeaderboardMetrics {
+			leaderboards[metric], _ = m.Publisher.GetLeaderboard(metric, window, limit)
+		}
+		writeJSON(w, leaderboards)
+		return
+	}
+
+	leaderboard, ok := m.Publisher.GetLeaderboard(metric, window, limit)
+	if !ok {
+		http.Error(w, "Unknown leaderboard metric", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, map[string]interface{}{
+		"metric":  metric,
+		"window":  window,
+		"entries": leaderboard,
+	})
+}
+
+// Returns the window query parameter (default all), writes the error response if the window is unknown
+func parseWindow(w http.ResponseWriter, r *http.Request) (string, bool) {
+	window := r.URL.Query().Get("window")
+	if window == "" {
+		return statistics.WINDOW_ALL, true
+	}
+	if !statistics.IsWindow(window) {
+		http.Error(w, "Unknown window", http.StatusBadRequest)
+		return "", false
+	}
+	return window, true
+}
@@ -0,0 +1,47 @@
+package listener
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Bitstarz-eng/event-processing-challenge/internal/statistics"
+)
+
+func TestLeaderboardsLimit(t *testing.T) {
+	m := newTestMaterialized(t)
+
+	leaderboard := getJSON(t, m.leaderboardsHandler, fmt.Sprintf("/leaderboards/bet_count?limit=%d", m.LeaderboardSize))
+	if entries, ok := leaderboard["entries"].([]interface{}); !ok || len(entries) != 1 {
+		t.Fatalf("bet_count entries = %v, want player 10", leaderboard["entries"])
+	}
+
+	for _, limit := range []string{"0", "-1", "ten", fmt.Sprint(m.LeaderboardSize + 1)} {
+		recorder := httptest.NewRecorder()
+		m.leaderboardsHandler(recorder, httptest.NewRequest(http.MethodGet, "/leaderboards/bet_count?limit="+limit, nil))
+		if recorder.Code != http.StatusBadRequest {
+			t.Fatalf("limit %s = %d, want 400", limit, recorder.Code)
+		}
+	}
+}
+
+func TestLeaderboardsWindow(t *testing.T) {
+	// The windows end at the latest event
+	t.Setenv("ALLOWED_LATENESS", "0s")
+	m := newTestMaterialized(t)
+
+	for _, window := range []string{statistics.WINDOW_5M, statistics.WINDOW_1H, statistics.WINDOW_24H} {
+		leaderboard := getJSON(t, m.leaderboardsHandler, "/leaderboards/bet_amount?window="+window)
+		entries, ok := leaderboard["entries"].([]interface{})
+		if leaderboard["window"] != window || !ok || len(entries) != 1 || entries[0].(map[string]interface{})["value"] != 1250.0 {
+			t.Fatalf("%s bet_amount leaderboard = %v, want player 10 with 1250", window, leaderboard)
+		}
+	}
+
+	recorder := httptest.NewRecorder()
+	m.leaderboardsHandler(recorder, httptest.NewRequest(http.MethodGet, "/leaderboards/bet_amount?window=2h", nil))
+	if recorder.Code != http.StatusBadRequest {
+		t.Fatalf("unknown window = %d, want 400", recorder.Code)
+	}
+}
@@ -7,26 +7,74 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
 
+	"github.com/Bitstarz-eng/event-processing-challenge/internal/config"
 	"github.com/Bitstarz-eng/event-processing-challenge/internal/publisher"
 )
 
+// Default and max number of players served by the leaderboards
+const DEFAULT_LEADERBOARD_SIZE = 10
+
 type Materialized struct {
-	Publisher *publisher.Publisher
+	Publisher         *publisher.Publisher
+	LeaderboardSize   int
+	HeartbeatInterval time.Duration
+	PushInterval      time.Duration
+	AllowedOrigins    []string
+
+	// Builds the stats pushed to the WebSocket clients
+	broadcaster *statsBroadcaster
+
+	// Closed when the server shuts down, so the event streams and the WebSockets end
+	shutdown chan struct{}
 }
 
 func NewMaterializedListener(p *publisher.Publisher) *Materialized {
-	return &Materialized{
-		Publisher: p,
+	leaderboardSize := config.GetInt("LEADERBOARD_SIZE", DEFAULT_LEADERBOARD_SIZE)
+	if leaderboardSize <= 0 {
+		log.Fatalf("LEADERBOARD_SIZE must be positive, got %d", leaderboardSize)
+	}
+
+	heartbeatInterval := config.GetDuration("STREAM_HEARTBEAT_INTERVAL", DEFAULT_STREAM_HEARTBEAT_INTERVAL)
+	if heartbeatInterval <= 0 {
+		log.Fatalf("STREAM_HEARTBEAT_INTERVAL must be positive, got %v", heartbeatInterval)
+	}
+
+	pushInterval := config.GetDuration("WS_PUSH_INTERVAL", DEFAULT_WS_PUSH_INTERVAL)
+	if pushInterval <= 0 {
+		log.Fatalf("WS_PUSH_INTERVAL must be positive, got %v", pushInterval)
+	}
+
+	var allowedOrigins []string
+	for _, origin := range strings.Split(config.GetString("WS_ALLOWED_ORIGINS", ""), ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			allowedOrigins = append(allowedOrigins, origin)
+		}
+	}
+
+	m := &Materialized{
+		Publisher:         p,
+		LeaderboardSize:   leaderboardSize,
+		HeartbeatInterval: heartbeatInterval,
+		PushInterval:      pushInterval,
+		AllowedOrigins:    allowedOrigins,
+		shutdown:          make(chan struct{}),
 	}
+	m.broadcaster = newStatsBroadcaster(m.statsGroup, pushInterval)
+	return m
 }
 
 func (m *Materialized) materializedHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method == http.MethodGet {
-		stats := m.Publisher.GetStats()
+		window, ok := parseWindow(w, r)
+		if !ok {
+			return
+		}
+		stats := m.Publisher.GetStats(window)
 
 		response, err := json.Marshal(stats)
 		if err != nil {
@@ -46,6 +94,18 @@ func (m *Materialized) ListenAndServe(wg *sync.WaitGroup) {
 	defer wg.Done()
 
 	http.HandleFunc("/materialized", m.materializedHandler)
+	http.HandleFunc("/materialized/games", m.materializedGamesHandler)
+	http.HandleFunc("/materialized/ws", m.webSocketHandler)
+	http.HandleFunc("/leaderboards", m.leaderboardsHandler)
+	http.HandleFunc("/leaderboards/", m.leaderboardsHandler)
+	http.HandleFunc("/games", m.gamesHandler)
+	http.HandleFunc("/games/", m.gamesHandler)
+	http.HandleFunc("/distributions", m.distributionsHandler)
+	http.HandleFunc("/distributions/", m.distributionsHandler)
+	http.HandleFunc("/sessions", m.sessionsHandler)
+	http.HandleFunc("/players", m.playersHandler)
+	http.HandleFunc("/players/", m.playersHandler)
+	http.HandleFunc("/events/stream", m.streamHandler)
 
 	// Create an HTTP server
 	server := &http.Server{
@@ -53,6 +113,13 @@ func (m *Materialized) ListenAndServe(wg *sync.WaitGroup) {
 		Handler: nil,     // Use the default ServeMux
 	}
 
+	// Shutdown waits for the open connections, the event streams and the WebSockets never end by themselves
+	server.RegisterOnShutdown(func() {
+		close(m.shutdown)
+	})
+
+	go m.broadcaster.run(m.shutdown)
+
 	// Start the server in a goroutine
 	go func() {
 		log.Println("Starting HTTP server on port 8080...")
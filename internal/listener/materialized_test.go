@@ -0,0 +1,110 @@
+package listener
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Bitstarz-eng/event-processing-challenge/internal/casino"
+	"github.com/Bitstarz-eng/event-processing-challenge/internal/publisher"
+	"github.com/Bitstarz-eng/event-processing-challenge/internal/statistics"
+	subs "github.com/Bitstarz-eng/event-processing-challenge/internal/subscribers"
+)
+
+// Listener of in-memory subscribers which handled a bet of 12.50 EUR and its win of 25 EUR on game 100
+func newTestMaterialized(t *testing.T) *Materialized {
+	t.Setenv("TIME_STATS_STORE", statistics.TIME_STATS_STORE_MEMORY)
+	t.Setenv("HLL_BACKEND", statistics.HLL_BACKEND_MEMORY)
+
+	base := func() *subs.BaseSubscriber {
+		return &subs.BaseSubscriber{Rejected: statistics.NewValidationStats()}
+	}
+	players := &subs.PlayerSubscriber{BaseSubscriber: base(), Statistics: statistics.NewPlayerAggregator()}
+	games := &subs.GameSubscriber{BaseSubscriber: base(), Statistics: statistics.NewGameAggregator()}
+	p := &publisher.Publisher{
+		Subscribers: map[string]subs.Subscriber{
+			subs.PLAYER_SUB:  players,
+			subs.GAME_SUB:    games,
+			subs.TIME_SUB:    &subs.TimeSubscriber{BaseSubscriber: base(), Statistics: statistics.NewTimeStats()},
+			subs.SESSION_SUB: &subs.SessionSubscriber{BaseSubscriber: base(), Statistics: statistics.NewSessionTracker(time.Minute)},
+		},
+		Rejected: statistics.NewValidationStats(),
+	}
+
+	now := time.Now()
+	for _, event := range []casino.Event{
+		{ID: 1, PlayerID: 10, GameID: 100, Type: casino.BET, Currency: "EUR", Amount: 1250, AmountEUR: 1250, HasWon: true, CreatedAt: now},
+		{ID: 2, PlayerID: 10, GameID: 100, Type: casino.WIN, Currency: "EUR", Amount: 2500, AmountEUR: 2500, BetID: 1, CreatedAt: now},
+	} {
+		event := event
+		players.Statistics.HandleEvent(&event)
+		games.Statistics.HandleEvent(&event)
+	}
+
+	return &Materialized{Publisher: p, LeaderboardSize: DEFAULT_LEADERBOARD_SIZE}
+}
+
+func getJSON(t *testing.T, handler http.HandlerFunc, target string) map[string]interface{} {
+	recorder := httptest.NewRecorder()
+	handler(recorder, httptest.NewRequest(http.MethodGet, target, nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("GET %s = %d, want 200", target, recorder.Code)
+	}
+
+	var response map[string]interface{}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("GET %s: %v", target, err)
+	}
+	return response
+}
+
+// The games part has the same shape in /materialized and /materialized/games, every amount in EUR
+func checkGameStats(t *testing.T, target string, response map[string]interface{}) {
+	mostBetted, ok := response["most_betted_game"].(map[string]interface{})
+	if !ok || mostBetted["id"] != 100.0 || mostBetted["amount_eur"] != 12.5 {
+		t.Fatalf("%s most_betted_game = %v, want game 100 with amount_eur 12.5", target, response["most_betted_game"])
+	}
+	if _, ok := mostBetted["amount"]; ok {
+		t.Fatalf("%s most_betted_game has an amount in cents", target)
+	}
+	if _, ok := response["most_played_game"].(map[string]interface{}); !ok {
+		t.Fatalf("%s most_played_game = %v, want an object", target, response["most_played_game"])
+	}
+	if _, ok := response["unique_players"].(map[string]interface{}); !ok {
+		t.Fatalf("%s unique_players = %v, want an object", target, response["unique_players"])
+	}
+
+	games, ok := response["games"].([]interface{})
+	if !ok || len(games) != 1 {
+		t.Fatalf("%s games = %v, want game 100", target, response["games"])
+	}
+	game := games[0].(map[string]interface{})
+	for field, want := range map[string]float64{
+		"id":          100,
+		"bet_count":   1,
+		"stakes_eur":  12.5,
+		"payouts_eur": 25,
+		"ggr_eur":     -12.5,
+		"rtp":         2,
+	} {
+		if game[field] != want {
+			t.Fatalf("%s game %s = %v, want %v", target, field, game[field], want)
+		}
+	}
+}
+
+func TestMaterializedGameStats(t *testing.T) {
+	m := newTestMaterialized(t)
+
+	materialized := getJSON(t, m.materializedHandler, "/materialized")
+	checkGameStats(t, "/materialized", materialized)
+	for _, field := range []string{"window", "top_player_bet", "ggr_eur", "total_events", "late_events", "invalid_events"} {
+		if _, ok := materialized[field]; !ok {
+			t.Fatalf("/materialized has no %s", field)
+		}
+	}
+
+	checkGameStats(t, "/materialized/games", getJSON(t, m.materializedGamesHandler, "/materialized/games"))
+}
@@ -0,0 +1,95 @@
+package listener
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Bitstarz-eng/event-processing-challenge/internal/statistics"
+)
+
+// Default and max number of players of a /players page
+const (
+	DEFAULT_PLAYERS_PAGE_SIZE = 50
+	MAX_PLAYERS_PAGE_SIZE     = 500
+)
+
+// Serves the players on /players?offset=N&limit=N&sort=F&order=asc|desc and the profile of one player on /players/{id}
+func (m *Materialized) playersHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/players"), "/")
+	if path != "" {
+		playerID, err := strconv.Atoi(path)
+		if err != nil {
+			http.Error(w, "Invalid player ID", http.StatusBadRequest)
+			return
+		}
+
+		profile, ok := m.Publisher.GetPlayerProfile(playerID)
+		if !ok {
+			http.Error(w, "Player not found", http.StatusNotFound)
+			return
+		}
+		writeJSON(w, profile)
+		return
+	}
+
+	query := r.URL.Query()
+
+	offset := 0
+	if value := query.Get("offset"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			http.Error(w, "Invalid offset", http.StatusBadRequest)
+			return
+		}
+		offset = parsed
+	}
+
+	limit := DEFAULT_PLAYERS_PAGE_SIZE
+	if value := query.Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 || parsed > MAX_PLAYERS_PAGE_SIZE {
+			http.Error(w, fmt.Sprintf("Invalid limit, must be between 1 and %d", MAX_PLAYERS_PAGE_SIZE), http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	field := query.Get("sort")
+	if field == "" {
+		field = statistics.PLAYER_SORT_ID
+	}
+	if !statistics.IsPlayerSort(field) {
+		http.Error(w, "Unknown sort field", http.StatusBadRequest)
+		return
+	}
+
+	// The IDs are ascending by default, the metrics descending
+	order := query.Get("order")
+	if order == "" {
+		order = "desc"
+		if field == statistics.PLAYER_SORT_ID {
+			order = "asc"
+		}
+	}
+	if order != "asc" && order != "desc" {
+		http.Error(w, "Invalid order", http.StatusBadRequest)
+		return
+	}
+
+	players, total := m.Publisher.GetPlayers(field, order == "desc", offset, limit)
+	writeJSON(w, map[string]interface{}{
+		"total":   total,
+		"offset":  offset,
+		"limit":   limit,
+		"sort":    field,
+		"order":   order,
+		"players": players,
+	})
+}
@@ -0,0 +1,28 @@
+package listener
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPlayersLimit(t *testing.T) {
+	m := newTestMaterialized(t)
+
+	page := getJSON(t, m.playersHandler, fmt.Sprintf("/players?limit=%d", MAX_PLAYERS_PAGE_SIZE))
+	if page["limit"] != float64(MAX_PLAYERS_PAGE_SIZE) {
+		t.Fatalf("limit = %v, want %d", page["limit"], MAX_PLAYERS_PAGE_SIZE)
+	}
+	if players, ok := page["players"].([]interface{}); !ok || len(players) == 0 {
+		t.Fatalf("players = %v, want the players of the events", page["players"])
+	}
+
+	for _, limit := range []string{"0", "-1", "ten", fmt.Sprint(MAX_PLAYERS_PAGE_SIZE + 1)} {
+		recorder := httptest.NewRecorder()
+		m.playersHandler(recorder, httptest.NewRequest(http.MethodGet, "/players?limit="+limit, nil))
+		if recorder.Code != http.StatusBadRequest {
+			t.Fatalf("limit %s = %d, want 400", limit, recorder.Code)
+		}
+	}
+}
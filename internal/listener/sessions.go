@@ -0,0 +1,12 @@
+package listener
+
+import "net/http"
+
+// Serves the active and closed game sessions on /sessions
+func (m *Materialized) sessionsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, m.Publisher.GetSessionStats())
+}
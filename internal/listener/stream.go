@@ -0,0 +1,162 @@
+package listener
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Bitstarz-eng/event-processing-challenge/internal/casino"
+	"github.com/Bitstarz-eng/event-processing-challenge/internal/stream"
+)
+
+// Default interval of the heartbeat comments, configured by STREAM_HEARTBEAT_INTERVAL
+const DEFAULT_STREAM_HEARTBEAT_INTERVAL = 15 * time.Second
+
+// Streams the enriched events as Server-Sent Events on /events/stream?type=T1,T2&player=ID&game=ID&min_amount_eur=X.
+// A client resumes after the Last-Event-ID header (or the last_event_id parameter) from the buffer of the latest events.
+func (m *Materialized) streamHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	filter, err := parseStreamFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("last_event_id")
+	}
+	var lastID uint64
+	if lastEventID != "" {
+		if lastID, err = strconv.ParseUint(lastEventID, 10, 64); err != nil {
+			http.Error(w, "Invalid Last-Event-ID", http.StatusBadRequest)
+			return
+		}
+	}
+
+	hub := m.Publisher.GetStream()
+	client, backlog, missed := hub.Subscribe(filter, lastID)
+	defer hub.Unsubscribe(client)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	if missed {
+		fmt.Fprintf(w, ": events after %d are no longer buffered\n\n", lastID)
+	}
+	for _, message := range backlog {
+		if err := writeStreamMessage(w, message); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(m.HeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case message, ok := <-client.Messages:
+			if !ok {
+				// The client fell behind or the server shuts down, it reconnects with Last-Event-ID
+				return
+			}
+			if err := writeStreamMessage(w, message); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-m.shutdown:
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func writeStreamMessage(w http.ResponseWriter, message stream.Message) error {
+	data, err := json.Marshal(message.Event)
+	if err != nil {
+		log.Printf("Failed to marshal streamed event %d: %v", message.Event.ID, err)
+		return nil
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", message.ID, message.Event.Type, data)
+	return err
+}
+
+func parseStreamFilter(r *http.Request) (stream.Filter, error) {
+	query := r.URL.Query()
+	var filter stream.Filter
+
+	if value := query.Get("type"); value != "" {
+		filter.Types = make(map[string]bool)
+		for _, eventType := range strings.Split(value, ",") {
+			eventType = strings.TrimSpace(eventType)
+			if !isEventType(eventType) {
+				return filter, fmt.Errorf("Unknown event type %q", eventType)
+			}
+			filter.Types[eventType] = true
+		}
+	}
+
+	if value := query.Get("player"); value != "" {
+		playerID, err := strconv.Atoi(value)
+		if err != nil || playerID <= 0 {
+			return filter, errors.New("Invalid player ID")
+		}
+		filter.PlayerID = playerID
+	}
+
+	if value := query.Get("game"); value != "" {
+		gameID, err := strconv.Atoi(value)
+		if err != nil {
+			return filter, errors.New("Invalid game ID")
+		}
+		if _, ok := casino.Games[gameID]; !ok {
+			return filter, fmt.Errorf("Unknown game %d", gameID)
+		}
+		filter.GameID = gameID
+	}
+
+	if value := query.Get("min_amount_eur"); value != "" {
+		// No event is above the largest amount, so larger values would only overflow the amount in cents
+		maxAmount := float64(casino.MaxAmount["EUR"]) * casino.SmallestUnit["EUR"]
+		amount, err := strconv.ParseFloat(value, 64)
+		if err != nil || math.IsNaN(amount) || math.IsInf(amount, 0) || amount < 0 || amount > maxAmount {
+			return filter, fmt.Errorf("Invalid min_amount_eur, must be between 0 and %.0f", maxAmount)
+		}
+		filter.MinAmountEUR = int(math.Round(amount / casino.SmallestUnit["EUR"]))
+	}
+	return filter, nil
+}
+
+func isEventType(eventType string) bool {
+	for _, t := range casino.EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
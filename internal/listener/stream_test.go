@@ -0,0 +1,24 @@
+package listener
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestStreamRejectsInvalidMinAmount(t *testing.T) {
+	m := newTestMaterialized(t)
+
+	for _, amount := range []string{"NaN", "Inf", "-Inf", "-1", "1e300", "ten"} {
+		recorder := httptest.NewRecorder()
+		m.streamHandler(recorder, httptest.NewRequest(http.MethodGet, "/events/stream?min_amount_eur="+amount, nil))
+		if recorder.Code != http.StatusBadRequest {
+			t.Fatalf("min_amount_eur %s = %d, want 400", amount, recorder.Code)
+		}
+	}
+
+	filter, err := parseStreamFilter(httptest.NewRequest(http.MethodGet, "/events/stream?min_amount_eur=12.5", nil))
+	if err != nil || filter.MinAmountEUR != 1250 {
+		t.Fatalf("min_amount_eur 12.5 = %d cents, %v, want 1250", filter.MinAmountEUR, err)
+	}
+}
@@ -0,0 +1,248 @@
+package listener
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Bitstarz-eng/event-processing-challenge/internal/publisher"
+	"github.com/Bitstarz-eng/event-processing-challenge/internal/statistics"
+	"github.com/gorilla/websocket"
+)
+
+// Default interval between the pushes of the changed stats, configured by WS_PUSH_INTERVAL
+const DEFAULT_WS_PUSH_INTERVAL = time.Second
+
+// Metric groups of the WebSocket, the groups of /materialized and the leaderboards and sessions
+const (
+	WS_GROUP_LEADERBOARDS = "leaderboards"
+	WS_GROUP_SESSIONS     = "sessions"
+)
+
+var WebSocketGroups = append(append([]string{}, publisher.StatsGroups...), WS_GROUP_LEADERBOARDS, WS_GROUP_SESSIONS)
+
+// Subscription actions of the client messages
+const (
+	WS_ACTION_SUBSCRIBE   = "subscribe"
+	WS_ACTION_UNSUBSCRIBE = "unsubscribe"
+)
+
+const (
+	// Time allowed to write a message to the client
+	WS_WRITE_WAIT = 10 * time.Second
+	// Time allowed to read the next pong from the client, the pings are sent before it expires
+	WS_PONG_WAIT   = 60 * time.Second
+	WS_PING_PERIOD = WS_PONG_WAIT * 9 / 10
+	// Largest client message
+	WS_MAX_MESSAGE_SIZE = 4096
+)
+
+// Client message changing the subscribed groups, and the window of the windowed groups if set
+type wsCommand struct {
+	Action string   `json:"action"`
+	Groups []string `json:"groups"`
+	Window string   `json:"window,omitempty"`
+}
+
+// Pushed stats of one group
+type wsMessage struct {
+	Group  string          `json:"group"`
+	Window string          `json:"window"`
+	Data   json.RawMessage `json:"data"`
+}
+
+type wsError struct {
+	Error string `json:"error"`
+}
+
+// Pushes the stats of the subscribed groups on /materialized/ws?groups=G1,G2&window=W whenever they change,
+// at most once per push interval. The groups are built once for all clients by the broadcaster. The client changes its groups with {"action":"subscribe|unsubscribe","groups":[...]}.
+func (m *Materialized) webSocketHandler(w http.ResponseWriter, r *http.Request) {
+	window, ok := parseWindow(w, r)
+	if !ok {
+		return
+	}
+
+	groups := make(map[string]bool)
+	if value := r.URL.Query().Get("groups"); value != "" {
+		for _, group := range strings.Split(value, ",") {
+			group = strings.TrimSpace(group)
+			if !isWebSocketGroup(group) {
+				http.Error(w, fmt.Sprintf("Unknown group %q", group), http.StatusBadRequest)
+				return
+			}
+			groups[group] = true
+		}
+	} else {
+		for _, group := range WebSocketGroups {
+			groups[group] = true
+		}
+	}
+
+	// Without configured origins the upgrader accepts only the same origin
+	upgrader := websocket.Upgrader{}
+	if len(m.AllowedOrigins) > 0 {
+		upgrader.CheckOrigin = m.checkOrigin
+	}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader already wrote the error response
+		log.Printf("WebSocket upgrade failed: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	client := m.broadcaster.register()
+	defer m.broadcaster.unregister(client)
+	m.broadcaster.subscribe(client, groups, window)
+
+	// Only the reader goroutine reads, only this goroutine writes
+	commands := make(chan wsCommand)
+	closed := make(chan struct{})
+	done := make(chan struct{})
+	defer close(done)
+	go readWebSocketCommands(conn, commands, closed, done)
+
+	ping := time.NewTicker(WS_PING_PERIOD)
+	defer ping.Stop()
+
+	for {
+		select {
+		case message, ok := <-client.messages:
+			if !ok {
+				// The client fell behind, it reconnects and gets the latest stats
+				conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "too slow"), time.Now().Add(WS_WRITE_WAIT))
+				return
+			}
+			conn.SetWriteDeadline(time.Now().Add(WS_WRITE_WAIT))
+			if err := conn.WritePreparedMessage(message); err != nil {
+				return
+			}
+		case command := <-commands:
+			if err := applyWebSocketCommand(command, groups, &window); err != nil {
+				if err := writeWebSocketJSON(conn, wsError{Error: err.Error()}); err != nil {
+					return
+				}
+				continue
+			}
+			m.broadcaster.subscribe(client, groups, window)
+		case <-ping.C:
+			conn.SetWriteDeadline(time.Now().Add(WS_WRITE_WAIT))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		case <-m.shutdown:
+			conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutdown"), time.Now().Add(WS_WRITE_WAIT))
+			return
+		}
+	}
+}
+
+// Reads the client messages until the connection fails, the pongs extend the read deadline
+func readWebSocketCommands(conn *websocket.Conn, commands chan<- wsCommand, closed, done chan struct{}) {
+	defer close(closed)
+
+	conn.SetReadLimit(WS_MAX_MESSAGE_SIZE)
+	conn.SetReadDeadline(time.Now().Add(WS_PONG_WAIT))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(WS_PONG_WAIT))
+	})
+
+	for {
+		var command wsCommand
+		if err := conn.ReadJSON(&command); err != nil {
+			switch err.(type) {
+			case *json.SyntaxError, *json.UnmarshalTypeError:
+				// Reported to the client as an unknown action
+				command = wsCommand{}
+			default:
+				if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
+					log.Printf("WebSocket read error: %v", err)
+				}
+				return
+			}
+		}
+
+		select {
+		case commands <- command:
+		case <-done:
+			return
+		}
+	}
+}
+
+// Updates the subscribed groups and the window
+func applyWebSocketCommand(command wsCommand, groups map[string]bool, window *string) error {
+	if command.Action != WS_ACTION_SUBSCRIBE && command.Action != WS_ACTION_UNSUBSCRIBE {
+		return fmt.Errorf("Unknown action %q", command.Action)
+	}
+	for _, group := range command.Groups {
+		if !isWebSocketGroup(group) {
+			return fmt.Errorf("Unknown group %q", group)
+		}
+	}
+	if command.Window != "" && !statistics.IsWindow(command.Window) {
+		return fmt.Errorf("Unknown window %q", command.Window)
+	}
+
+	if command.Window != "" {
+		*window = command.Window
+	}
+	for _, group := range command.Groups {
+		if command.Action == WS_ACTION_SUBSCRIBE {
+			groups[group] = true
+		} else {
+			delete(groups, group)
+		}
+	}
+	return nil
+}
+
+// Builds the stats of one group in the window
+func (m *Materialized) statsGroup(group, window string) interface{} {
+	switch group {
+	case WS_GROUP_LEADERBOARDS:
+		leaderboards := make(map[string][]statistics.LeaderboardEntry, len(statistics.LeaderboardMetrics))
+		for _, metric := range statistics.LeaderboardMetrics {
+			leaderboards[metric], _ = m.Publisher.GetLeaderboard(metric, window, m.LeaderboardSize)
+		}
+		return leaderboards
+	case WS_GROUP_SESSIONS:
+		return m.Publisher.GetSessionStats()
+	default:
+		stats, _ := m.Publisher.GetStatsGroup(group, window)
+		return stats
+	}
+}
+
+func writeWebSocketJSON(conn *websocket.Conn, value interface{}) error {
+	conn.SetWriteDeadline(time.Now().Add(WS_WRITE_WAIT))
+	return conn.WriteJSON(value)
+}
+
+// Accepts the configured origins, * accepts any origin
+func (m *Materialized) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	for _, allowed := range m.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+func isWebSocketGroup(group string) bool {
+	for _, g := range WebSocketGroups {
+		if g == group {
+			return true
+		}
+	}
+	return false
+}
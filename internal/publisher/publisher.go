@@ -2,16 +2,18 @@ package publisher
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"log"
 	"sync"
 	"time"
 
 	"github.com/Bitstarz-eng/event-processing-challenge/internal/casino"
 	"github.com/Bitstarz-eng/event-processing-challenge/internal/db"
-	"github.com/Bitstarz-eng/event-processing-challenge/internal/generator"
 	rds "github.com/Bitstarz-eng/event-processing-challenge/internal/redis"
 	"github.com/Bitstarz-eng/event-processing-challenge/internal/statistics"
+	"github.com/Bitstarz-eng/event-processing-challenge/internal/stream"
 	subs "github.com/Bitstarz-eng/event-processing-challenge/internal/subscribers"
 	"github.com/go-redis/redis/v8"
 )
@@ -20,11 +22,44 @@ type Publisher struct {
 	RedisClient *redis.Client
 	Subscribers map[string]subs.Subscriber
 	DB          *db.DB
+
+	// Invalid events per validation rule, they are quarantined instead of published
+	Rejected *statistics.ValidationStats
+
+	// Optional callback after every publish attempt, e.g. for the load report
+	OnPublish func(event *casino.Event, err error)
 }
 
 const CASINO_EVENT_CHANNEL = "casino_event"
 const STOP_SIGNAL = "stop_casino_event"
 
+// Redis list of the quarantined (invalid) events, only the latest events are kept
+const QUARANTINE_LIST = "casino_event_quarantine"
+const QUARANTINE_SIZE = 10000
+
+// Longest description kept in the quarantined event
+const QUARANTINE_DESCRIPTION_LENGTH = 1024
+
+// Groups of the combined stats of /materialized
+const (
+	STATS_GROUP_PLAYERS        = "players"
+	STATS_GROUP_TIME           = "time"
+	STATS_GROUP_GAMES          = "games"
+	STATS_GROUP_INVALID_EVENTS = "invalid_events"
+)
+
+var StatsGroups = []string{STATS_GROUP_PLAYERS, STATS_GROUP_TIME, STATS_GROUP_GAMES, STATS_GROUP_INVALID_EVENTS}
+
+// Number of the latest stored events in the player profile
+const RECENT_PLAYER_EVENTS = 10
+
+// PlayerProfile is the player of the DB with the statistics and the latest stored events of the player
+type PlayerProfile struct {
+	*statistics.PlayerProfile
+	Player       *casino.Player `json:"player"`
+	RecentEvents []casino.Event `json:"recent_events"`
+}
+
 func NewPublisher() *Publisher {
 	redisClient := rds.GetRedisClient()
 	subscribers := subs.GetSubscribers()
@@ -34,18 +69,24 @@ func NewPublisher() *Publisher {
 		RedisClient: redisClient,
 		Subscribers: subscribers,
 		DB:          db,
+		Rejected:    statistics.NewValidationStats(),
 	}
 }
 
-func (p *Publisher) StartPublishing(ctx context.Context, wg *sync.WaitGroup) {
+// Publish the events from the channel until it is closed
+func (p *Publisher) StartPublishing(eventCh <-chan casino.Event, wg *sync.WaitGroup) {
 	defer wg.Done()
 
-	eventCh := generator.Generate(ctx)
-
 	redisCtx := context.Background()
 
 	go p.startSubscription(redisCtx)
 	for event := range eventCh {
+		// Quarantine invalid events
+		if err := event.Validate(); err != nil {
+			p.quarantine(redisCtx, &event, err)
+			continue
+		}
+
 		// Process event data
 		p.processEvent(&event)
 
@@ -60,6 +101,9 @@ func (p *Publisher) StartPublishing(ctx context.Context, wg *sync.WaitGroup) {
 		if err != nil {
 			log.Printf("Failed to publish message: %v", err)
 		}
+		if p.OnPublish != nil {
+			p.OnPublish(&event, err)
+		}
 		log.Println(event)
 	}
 	go p.stopSubscription(redisCtx)
@@ -89,10 +133,37 @@ func (p *Publisher) stopSubscription(ctx context.Context) {
 	}
 }
 
+// Store the invalid event with the broken rule in the quarantine list
+func (p *Publisher) quarantine(ctx context.Context, event *casino.Event, err error) {
+	rule := casino.ValidationRule(err)
+	p.Rejected.Add(rule)
+	log.Printf("Quarantined event %d: %v", event.ID, err)
+
+	if len(event.Description) > QUARANTINE_DESCRIPTION_LENGTH {
+		event.Description = event.Description[:QUARANTINE_DESCRIPTION_LENGTH]
+	}
+	entryJSON, marshalErr := json.Marshal(map[string]interface{}{
+		"rule":  rule,
+		"error": err.Error(),
+		"event": event,
+	})
+	if marshalErr != nil {
+		log.Printf("Failed to marshal quarantined event: %v", marshalErr)
+		return
+	}
+
+	pipe := p.RedisClient.TxPipeline()
+	pipe.LPush(ctx, QUARANTINE_LIST, entryJSON)
+	pipe.LTrim(ctx, QUARANTINE_LIST, 0, QUARANTINE_SIZE-1)
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("Failed to quarantine event %d: %v", event.ID, err)
+	}
+}
+
 // Set common currency, find the player data and set description
 func (p *Publisher) processEvent(event *casino.Event) {
-	// Calculate AmountEUR for BET and DEPOSIT events
-	if event.Type == casino.BET || event.Type == casino.DEPOSIT {
+	// Calculate AmountEUR for the events with an amount
+	if event.HasAmount() {
 		EUR := casino.Currencies[0]
 		if event.Currency == EUR {
 			event.AmountEUR = event.Amount
@@ -143,22 +214,147 @@ func (p *Publisher) getExchangedValue(from, to string, amount int) float64 {
 	return exchangeRateResponse.Result
 }
 
-func (p *Publisher) GetStats() interface{} {
-	playerStats := p.Subscribers[subs.PLAYER_SUB].GetStats().(*statistics.PlayerStats)
-	timeStats := p.Subscribers[subs.TIME_SUB].GetStats().(*statistics.TimeStats)
-
+// GetStats returns the combined statistics, the top players are calculated over the window
+func (p *Publisher) GetStats(window string) interface{} {
 	// Create combined Stats
 	response := make(map[string]interface{})
-	response["top_player_bet"] = playerStats.TopPlayerBet
-	response["top_player_deposit"] = playerStats.TopPlayerDeposit
-	response["top_player_win"] = playerStats.TopPlayerWin
-	response["total_events"] = timeStats.TotalEvents
-	response["events_per_minute"] = timeStats.EventsPerMinute
-	response["moving_avg_per_second"] = timeStats.MovingAvgPerSecond
-
+	response["window"] = window
+	for _, group := range StatsGroups {
+		stats, _ := p.GetStatsGroup(group, window)
+		for key, value := range stats {
+			response[key] = value
+		}
+	}
 	return response
 }
 
+// GetStatsGroup returns one group of the combined stats in the window, false if the group is unknown
+func (p *Publisher) GetStatsGroup(group, window string) (map[string]interface{}, bool) {
+	playerStats := p.Subscribers[subs.PLAYER_SUB].GetStats().(*statistics.PlayerAggregator)
+	response := make(map[string]interface{})
+
+	switch group {
+	case STATS_GROUP_PLAYERS:
+		response["top_player_bet"] = playerStats.TopPlayerBet(window)
+		response["top_player_deposit"] = playerStats.TopPlayerDeposit(window)
+		response["top_player_win"] = playerStats.TopPlayerWin(window)
+		response["ggr_eur"] = playerStats.GGR()
+		response["net_deposits_eur"] = playerStats.NetDeposits()
+	case STATS_GROUP_TIME:
+		timeStats := p.Subscribers[subs.TIME_SUB].GetStats().(*statistics.TimeStats)
+		response["total_events"] = timeStats.TotalEvents
+		response["events_per_minute"] = timeStats.EventsPerMinute
+		response["moving_avg_per_second"] = timeStats.MovingAvgPerSecond
+		response["rates"] = timeStats.Rates
+		response["watermark"] = timeStats.WatermarkTime
+		response["late_events"] = map[string]int64{
+			subs.TIME_SUB:   timeStats.LateEvents,
+			subs.PLAYER_SUB: playerStats.Windows.LateEvents(),
+		}
+	case STATS_GROUP_GAMES:
+		gameStats := p.GetGameStats()
+		response["most_played_game"] = gameStats.MostPlayedGame
+		response["most_betted_game"] = gameStats.MostBettedGame
+		response["games"] = gameStats.Games
+		response["unique_players"] = gameStats.UniquePlayers
+	case STATS_GROUP_INVALID_EVENTS:
+		// Invalid events, quarantined by the publisher and rejected by each subscriber
+		rejectedBySubscriber := make(map[string]map[string]int64)
+		for name, subscriber := range p.Subscribers {
+			rejectedBySubscriber[name] = subscriber.GetRejected()
+		}
+		response["invalid_events"] = map[string]interface{}{
+			"quarantined": p.Rejected.Snapshot(),
+			"rejected":    rejectedBySubscriber,
+		}
+	default:
+		return nil, false
+	}
+	return response, true
+}
+
+// GetLeaderboard returns the first limit players of the metric leaderboard in the window, false if the metric is unknown
+func (p *Publisher) GetLeaderboard(metric, window string, limit int) ([]statistics.LeaderboardEntry, bool) {
+	playerStats := p.Subscribers[subs.PLAYER_SUB].GetStats().(*statistics.PlayerAggregator)
+	if !playerStats.IsMetric(metric) {
+		return nil, false
+	}
+	return playerStats.Top(metric, window, limit), true
+}
+
+// GetPlayerProfile returns the profile of the player, false if the player has neither events nor a DB record
+func (p *Publisher) GetPlayerProfile(playerID int) (*PlayerProfile, bool) {
+	playerStats := p.Subscribers[subs.PLAYER_SUB].GetStats().(*statistics.PlayerAggregator)
+	stats, hasEvents := playerStats.Profile(playerID)
+
+	player, err := p.DB.GetPlayer(playerID)
+	if errors.Is(err, sql.ErrNoRows) {
+		player = nil
+	} else if err != nil {
+		log.Printf("Failed to get player data for ID %d: %v", playerID, err)
+		player = nil
+	}
+
+	if !hasEvents && player == nil {
+		return nil, false
+	}
+	if !hasEvents {
+		stats = &statistics.PlayerProfile{ID: playerID}
+	}
+
+	// The events of the current batch of the event store are not stored yet
+	recentEvents, err := p.DB.GetRecentEventsByPlayer(playerID, RECENT_PLAYER_EVENTS)
+	if err != nil {
+		log.Printf("Failed to get recent events of player %d: %v", playerID, err)
+	}
+	if recentEvents == nil {
+		recentEvents = []casino.Event{}
+	}
+
+	return &PlayerProfile{
+		PlayerProfile: stats,
+		Player:        player,
+		RecentEvents:  recentEvents,
+	}, true
+}
+
+// GetPlayers returns the limit players from the offset sorted by the field, and the number of all players
+func (p *Publisher) GetPlayers(field string, descending bool, offset, limit int) ([]statistics.PlayerSummary, int) {
+	playerStats := p.Subscribers[subs.PLAYER_SUB].GetStats().(*statistics.PlayerAggregator)
+	return playerStats.PlayersPage(field, descending, offset, limit)
+}
+
+// GetDistribution returns the distribution of the bet or deposit amounts, false if the metric is unknown
+func (p *Publisher) GetDistribution(metric string) (*statistics.DistributionStats, bool) {
+	playerStats := p.Subscribers[subs.PLAYER_SUB].GetStats().(*statistics.PlayerAggregator)
+	return playerStats.Distributions.Stats(metric)
+}
+
+// GetSessionStats returns the active and closed game sessions
+func (p *Publisher) GetSessionStats() *statistics.SessionStats {
+	return p.Subscribers[subs.SESSION_SUB].GetStats().(*statistics.SessionStats)
+}
+
+// GetStream returns the hub of the enriched event stream
+func (p *Publisher) GetStream() *stream.Hub {
+	return p.Subscribers[subs.STREAM_SUB].(*subs.StreamSubscriber).Hub
+}
+
+// GetGameStats returns the most played game, the most betted game, the statistics of each game and the unique players
+func (p *Publisher) GetGameStats() *statistics.GameStats {
+	return p.Subscribers[subs.GAME_SUB].GetStats().(*statistics.GameStats)
+}
+
+// GetGames returns the GGR and RTP statistics of all played games
+func (p *Publisher) GetGames() []statistics.GameSummary {
+	return p.Subscribers[subs.GAME_SUB].(*subs.GameSubscriber).GetGames()
+}
+
+// GetGame returns the GGR and RTP statistics of the game, false if the game is unknown
+func (p *Publisher) GetGame(gameId int) (statistics.GameSummary, bool) {
+	return p.Subscribers[subs.GAME_SUB].(*subs.GameSubscriber).GetGame(gameId)
+}
+
 // Show the stat in the console, testing purpose
 func (p *Publisher) ShowStats() {
 	for _, sub := range p.Subscribers {
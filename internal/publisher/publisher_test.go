@@ -0,0 +1,111 @@
+package publisher
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Bitstarz-eng/event-processing-challenge/internal/casino"
+	"github.com/Bitstarz-eng/event-processing-challenge/internal/statistics"
+	"github.com/go-redis/redis/v8"
+)
+
+var errNoRedis = errors.New("redis is not available in the tests")
+
+// recordingHook keeps the commands sent to Redis and fails them before they reach the network
+type recordingHook struct {
+	mu       sync.Mutex
+	commands [][]interface{}
+}
+
+func (rh *recordingHook) record(cmds ...redis.Cmder) {
+	rh.mu.Lock()
+	defer rh.mu.Unlock()
+	for _, cmd := range cmds {
+		rh.commands = append(rh.commands, cmd.Args())
+	}
+}
+
+func (rh *recordingHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	rh.record(cmd)
+	return ctx, errNoRedis
+}
+
+func (rh *recordingHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	return nil
+}
+
+func (rh *recordingHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	rh.record(cmds...)
+	return ctx, errNoRedis
+}
+
+func (rh *recordingHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	return nil
+}
+
+// Returns the recorded commands with the name
+func (rh *recordingHook) find(name string) [][]interface{} {
+	rh.mu.Lock()
+	defer rh.mu.Unlock()
+	var found [][]interface{}
+	for _, args := range rh.commands {
+		if args[0] == name {
+			found = append(found, args)
+		}
+	}
+	return found
+}
+
+func TestPublishQuarantinesInvalidEvents(t *testing.T) {
+	hook := &recordingHook{}
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:0"})
+	client.AddHook(hook)
+	defer client.Close()
+
+	var published []int
+	p := &Publisher{
+		RedisClient: client,
+		Rejected:    statistics.NewValidationStats(),
+		OnPublish:   func(event *casino.Event, err error) { published = append(published, event.ID) },
+	}
+
+	eventCh := make(chan casino.Event, 1)
+	eventCh <- casino.Event{ID: 1, PlayerID: 10, Type: casino.BET, GameID: 100, Currency: "XYZ", Amount: 100, CreatedAt: time.Now(), Description: strings.Repeat("a", 2*QUARANTINE_DESCRIPTION_LENGTH)}
+	close(eventCh)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	p.StartPublishing(eventCh, &wg)
+
+	if len(published) != 0 {
+		t.Fatalf("published events %v, want the invalid event quarantined", published)
+	}
+	if rejected := p.Rejected.Snapshot(); rejected[casino.RULE_UNKNOWN_CURRENCY] != 1 {
+		t.Fatalf("rejected = %v, want one unknown currency", rejected)
+	}
+
+	pushes, trims := hook.find("lpush"), hook.find("ltrim")
+	if len(pushes) != 1 || pushes[0][1] != QUARANTINE_LIST {
+		t.Fatalf("LPUSH commands = %v, want one to %s", pushes, QUARANTINE_LIST)
+	}
+	if len(trims) != 1 || trims[0][1] != QUARANTINE_LIST || trims[0][2] != int64(0) || trims[0][3] != int64(QUARANTINE_SIZE-1) {
+		t.Fatalf("LTRIM commands = %v, want the list trimmed to %d events", trims, QUARANTINE_SIZE)
+	}
+
+	var entry struct {
+		Rule  string       `json:"rule"`
+		Error string       `json:"error"`
+		Event casino.Event `json:"event"`
+	}
+	if err := json.Unmarshal(pushes[0][2].([]byte), &entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry.Rule != casino.RULE_UNKNOWN_CURRENCY || entry.Event.ID != 1 || len(entry.Event.Description) != QUARANTINE_DESCRIPTION_LENGTH {
+		t.Fatalf("quarantined entry = %s %d with a description of %d bytes", entry.Rule, entry.Event.ID, len(entry.Event.Description))
+	}
+}
@@ -0,0 +1,224 @@
+package replay
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"github.com/Bitstarz-eng/event-processing-challenge/internal/casino"
+	"github.com/Bitstarz-eng/event-processing-challenge/internal/db"
+)
+
+// Max size of one JSON line in the recording
+const MAX_LINE_SIZE = 1024 * 1024
+
+// Stored events queried at once
+const STORE_PAGE_SIZE = 1000
+
+// Longest gap between two events which is paced, e.g. the far-future events of the generator faults are emitted right away
+const MAX_REPLAY_GAP = time.Minute
+
+// Filter selects the replayed events, zero-value fields are ignored
+type Filter struct {
+	From      time.Time
+	To        time.Time
+	PlayerIDs map[int]bool
+	Types     map[string]bool
+}
+
+func (f Filter) Match(event *casino.Event) bool {
+	if !f.From.IsZero() && event.CreatedAt.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && !event.CreatedAt.Before(f.To) {
+		return false
+	}
+	if len(f.PlayerIDs) > 0 && !f.PlayerIDs[event.PlayerID] {
+		return false
+	}
+	if len(f.Types) > 0 && !f.Types[event.Type] {
+		return false
+	}
+	return true
+}
+
+type Options struct {
+	// Replay speed relative to the original pacing:
+	// 1 keeps the original pacing, N replays N times faster, 0 replays as fast as possible
+	Speed  float64
+	Filter Filter
+}
+
+// FromFile replays the events recorded in the JSON-lines file
+func FromFile(ctx context.Context, path string, opts Options) (<-chan casino.Event, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	sourceCh := make(chan casino.Event)
+	go func() {
+		defer close(sourceCh)
+		defer file.Close()
+
+		reader := bufio.NewReader(file)
+		line := 0
+		for {
+			data, tooLong, err := readLine(reader)
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				log.Printf("Error reading %s: %v", path, err)
+				return
+			}
+
+			line++
+			if tooLong {
+				log.Printf("Skipping line %d of %s: longer than %d bytes", line, path, MAX_LINE_SIZE)
+				continue
+			}
+			if len(data) == 0 {
+				continue
+			}
+
+			var event casino.Event
+			if err := json.Unmarshal(data, &event); err != nil {
+				log.Printf("Skipping line %d of %s: %v", line, path, err)
+				continue
+			}
+
+			select {
+			case sourceCh <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return pace(ctx, sourceCh, opts), nil
+}
+
+// Reads the next line without the line break.
+// A line longer than MAX_LINE_SIZE is read to its end and reported as too long, so the following lines can be read.
+func readLine(reader *bufio.Reader) ([]byte, bool, error) {
+	var line []byte
+	tooLong := false
+	for {
+		chunk, isPrefix, err := reader.ReadLine()
+		if err != nil {
+			return nil, false, err
+		}
+		if !tooLong {
+			line = append(line, chunk...)
+			if len(line) > MAX_LINE_SIZE {
+				line, tooLong = nil, true
+			}
+		}
+		if !isPrefix {
+			return line, tooLong, nil
+		}
+	}
+}
+
+// FromStore replays the events stored in the event store, page by page
+func FromStore(ctx context.Context, store *db.DB, opts Options) (<-chan casino.Event, error) {
+	filter := db.EventFilter{
+		From:  opts.Filter.From,
+		To:    opts.Filter.To,
+		Limit: STORE_PAGE_SIZE,
+	}
+	for id := range opts.Filter.PlayerIDs {
+		filter.PlayerIDs = append(filter.PlayerIDs, id)
+	}
+	for eventType := range opts.Filter.Types {
+		filter.Types = append(filter.Types, eventType)
+	}
+
+	events, cursor, err := store.QueryEventsPage(filter)
+	if err != nil {
+		return nil, fmt.Errorf("query event store: %w", err)
+	}
+
+	sourceCh := make(chan casino.Event)
+	go func() {
+		defer close(sourceCh)
+		for {
+			for _, event := range events {
+				select {
+				case sourceCh <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+			if len(events) < STORE_PAGE_SIZE {
+				return
+			}
+
+			filter.After = cursor
+			if events, cursor, err = store.QueryEventsPage(filter); err != nil {
+				log.Printf("Error querying the event store: %v", err)
+				return
+			}
+		}
+	}()
+
+	return pace(ctx, sourceCh, opts), nil
+}
+
+// Filter the source events and emit them at their original offsets from the previous event, scaled by the speed.
+// Events older than the previous event, or more than MAX_REPLAY_GAP newer, are emitted right away and do not move the pacing.
+// If the next event follows such a newer event, the traffic really jumped and the pacing continues from there.
+func pace(ctx context.Context, sourceCh <-chan casino.Event, opts Options) <-chan casino.Event {
+	eventCh := make(chan casino.Event)
+
+	go func() {
+		defer close(eventCh)
+
+		// Time of the last paced event and when it was emitted, and the same of the last jump ahead
+		var last, lastAt, jump, jumpAt time.Time
+		for event := range sourceCh {
+			if !opts.Filter.Match(&event) {
+				continue
+			}
+
+			if opts.Speed > 0 {
+				if last.IsZero() {
+					last, lastAt = event.CreatedAt, time.Now()
+				}
+				if gap := event.CreatedAt.Sub(jump); !jump.IsZero() && gap >= 0 && gap <= MAX_REPLAY_GAP {
+					last, lastAt = jump, jumpAt
+				}
+
+				gap := event.CreatedAt.Sub(last)
+				switch {
+				case gap > MAX_REPLAY_GAP:
+					jump, jumpAt = event.CreatedAt, time.Now()
+				case gap >= 0:
+					jump = time.Time{}
+					last, lastAt = event.CreatedAt, lastAt.Add(time.Duration(float64(gap)/opts.Speed))
+					if wait := time.Until(lastAt); wait > 0 {
+						select {
+						case <-time.After(wait):
+						case <-ctx.Done():
+							return
+						}
+					}
+				}
+			}
+
+			select {
+			case eventCh <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return eventCh
+}
@@ -0,0 +1,83 @@
+package replay
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Bitstarz-eng/event-processing-challenge/internal/casino"
+)
+
+func replayed(events []casino.Event, speed float64) ([]int, time.Duration) {
+	sourceCh := make(chan casino.Event, len(events))
+	for _, event := range events {
+		sourceCh <- event
+	}
+	close(sourceCh)
+
+	start := time.Now()
+	var ids []int
+	for event := range pace(context.Background(), sourceCh, Options{Speed: speed}) {
+		ids = append(ids, event.ID)
+	}
+	return ids, time.Since(start)
+}
+
+func TestPaceEmitsOutOfRangeEventsRightAway(t *testing.T) {
+	start := time.Now()
+	events := []casino.Event{
+		{ID: 1, CreatedAt: start},
+		{ID: 2, CreatedAt: start.Add(365 * 24 * time.Hour)}, // Future timestamp fault
+		{ID: 3, CreatedAt: start.Add(50 * time.Millisecond)},
+		{ID: 4, CreatedAt: start.Add(-10 * time.Minute)}, // Out-of-order fault
+		{ID: 5, CreatedAt: start.Add(100 * time.Millisecond)},
+	}
+
+	ids, took := replayed(events, 1)
+	if len(ids) != 5 {
+		t.Fatalf("replayed %v, want all 5 events", ids)
+	}
+	if took < 100*time.Millisecond || took > time.Second {
+		t.Fatalf("replay took %v, want the 100ms of the in-range events", took)
+	}
+}
+
+func TestPaceContinuesAfterJump(t *testing.T) {
+	start := time.Now()
+	events := []casino.Event{
+		{ID: 1, CreatedAt: start},
+		{ID: 2, CreatedAt: start.Add(2 * time.Hour)}, // Traffic paused for 2 hours
+		{ID: 3, CreatedAt: start.Add(2*time.Hour + 100*time.Millisecond)},
+	}
+
+	if ids, took := replayed(events, 1); len(ids) != 3 || took < 100*time.Millisecond || took > time.Second {
+		t.Fatalf("replayed %v in %v, want 3 events paced 100ms after the jump", ids, took)
+	}
+}
+
+func TestFromFileSkipsLongLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	recording := `{"id":1,"player_id":10,"type":"deposit"}` + "\n" +
+		`{"id":2,"description":"` + strings.Repeat("x", MAX_LINE_SIZE) + `"}` + "\n" +
+		"\n" +
+		`{"id":3,"player_id":10,"type":"deposit"}`
+	if err := os.WriteFile(path, []byte(recording), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	eventCh, err := FromFile(context.Background(), path, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []int
+	for event := range eventCh {
+		ids = append(ids, event.ID)
+	}
+	if len(ids) != 2 || ids[0] != 1 || ids[1] != 3 {
+		t.Fatalf("replayed %v, want 1 and 3", ids)
+	}
+}
@@ -0,0 +1,69 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Snapshot is the file with the state of one subscriber
+type Snapshot struct {
+	// Version of the state format, a snapshot with another version is rejected
+	Version   int             `json:"version"`
+	Name      string          `json:"name"`
+	CreatedAt time.Time       `json:"created_at"`
+	State     json.RawMessage `json:"state"`
+}
+
+// Path returns the snapshot file of the subscriber in the directory
+func Path(dir, name string) string {
+	return filepath.Join(dir, name+".json")
+}
+
+// Save writes the state to the snapshot file, the previous snapshot is replaced only when the new one is complete
+func Save(path, name string, version int, state interface{}) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("marshal state: %w", err)
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	err = json.NewEncoder(file).Encode(Snapshot{
+		Version:   version,
+		Name:      name,
+		CreatedAt: time.Now(),
+		State:     data,
+	})
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), path)
+}
+
+// Load returns the state of the snapshot file, os.ErrNotExist if there is no snapshot
+func Load(path string, version int) (json.RawMessage, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshot Snapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("unmarshal snapshot: %w", err)
+	}
+	if snapshot.Version != version {
+		return nil, fmt.Errorf("incompatible snapshot version %d, expected %d", snapshot.Version, version)
+	}
+	return snapshot.State, nil
+}
@@ -0,0 +1,25 @@
+package snapshot
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestLoadRejectsOtherVersions(t *testing.T) {
+	path := Path(t.TempDir(), "players")
+	if err := Save(path, "players", 2, map[string]int{"bets": 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	state, err := Load(path, 2)
+	if err != nil || string(state) != `{"bets":3}` {
+		t.Fatalf("load = %s, %v, want the saved state", state, err)
+	}
+	if _, err := Load(path, 3); err == nil {
+		t.Fatal("snapshot of version 2 loaded as version 3")
+	}
+	if _, err := Load(Path(t.TempDir(), "games"), 2); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("load of a missing snapshot = %v, want os.ErrNotExist", err)
+	}
+}
@@ -0,0 +1,109 @@
+package statistics
+
+import (
+	"math"
+	"sort"
+)
+
+// Relative accuracy of the quantiles: a quantile is within 1% of the exact value
+const DDSKETCH_RELATIVE_ACCURACY = 0.01
+
+// DDSketch estimates the quantiles of positive values with a relative accuracy.
+// A value is counted in the bin ceil(log_gamma(value)), so the bins grow with the values and the memory with their range, not their number.
+// It is not safe for concurrent use.
+type DDSketch struct {
+	gamma     float64
+	logGamma  float64
+	bins      map[int]int64
+	zeroCount int64 // Values too small for a bin, e.g. 0
+	count     int64
+	sum       float64
+	min       float64
+	max       float64
+}
+
+func NewDDSketch() *DDSketch {
+	gamma := (1 + DDSKETCH_RELATIVE_ACCURACY) / (1 - DDSKETCH_RELATIVE_ACCURACY)
+	return &DDSketch{
+		gamma:    gamma,
+		logGamma: math.Log(gamma),
+		bins:     make(map[int]int64),
+	}
+}
+
+func (s *DDSketch) Add(value float64) {
+	if s.count == 0 || value < s.min {
+		s.min = value
+	}
+	if s.count == 0 || value > s.max {
+		s.max = value
+	}
+	s.count++
+	s.sum += value
+
+	if value <= 0 {
+		s.zeroCount++
+		return
+	}
+	s.bins[int(math.Ceil(math.Log(value)/s.logGamma))]++
+}
+
+// Quantile returns the estimated value of the quantile q in [0, 1], 0 if the sketch is empty
+func (s *DDSketch) Quantile(q float64) float64 {
+	if s.count == 0 {
+		return 0
+	}
+
+	rank := int64(q * float64(s.count-1))
+	if rank < s.zeroCount {
+		return s.min
+	}
+
+	seen := s.zeroCount
+	for _, index := range s.indexes() {
+		seen += s.bins[index]
+		if seen > rank {
+			// The middle of the bin in relative terms, clamped to the values seen
+			value := 2 * math.Pow(s.gamma, float64(index)) / (s.gamma + 1)
+			return math.Max(s.min, math.Min(s.max, value))
+		}
+	}
+	return s.max
+}
+
+func (s *DDSketch) Count() int64 {
+	return s.count
+}
+
+func (s *DDSketch) indexes() []int {
+	indexes := make([]int, 0, len(s.bins))
+	for index := range s.bins {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+	return indexes
+}
+
+// QuantileSummary is the read-only view of a sketch
+type QuantileSummary struct {
+	Count int64   `json:"count"`
+	Mean  float64 `json:"mean"`
+	P50   float64 `json:"p50"`
+	P90   float64 `json:"p90"`
+	P99   float64 `json:"p99"`
+	Max   float64 `json:"max"`
+}
+
+func (s *DDSketch) Summary() QuantileSummary {
+	summary := QuantileSummary{
+		Count: s.count,
+		P50:   s.Quantile(0.5),
+		P90:   s.Quantile(0.9),
+		P99:   s.Quantile(0.99),
+		Max:   s.max,
+	}
+	if s.count > 0 {
+		summary.Mean = s.sum / float64(s.count)
+	}
+	return summary
+}
@@ -0,0 +1,38 @@
+package statistics
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDDSketchQuantiles(t *testing.T) {
+	s := NewDDSketch()
+	for value := 1; value <= 10000; value++ {
+		s.Add(float64(value) / 100) // 0.01 to 100 EUR
+	}
+
+	for _, q := range []float64{0, 0.5, 0.9, 0.99, 1} {
+		exact := math.Floor(q*9999+1) / 100
+		if got := s.Quantile(q); math.Abs(got-exact) > DDSKETCH_RELATIVE_ACCURACY*exact {
+			t.Fatalf("quantile %.2f = %.4f, want %.4f within 1%%", q, got, exact)
+		}
+	}
+}
+
+func TestDistributionHistogram(t *testing.T) {
+	d := NewDistribution()
+	for _, amount := range []float64{0.5, 1, 7, 20000} {
+		d.Add(amount)
+	}
+
+	summary := d.Summary()
+	if summary.Histogram[0].Count != 2 || summary.Histogram[2].Count != 1 || summary.Histogram[len(summary.Histogram)-1].Count != 1 {
+		t.Fatalf("histogram = %+v, want 2 amounts up to 1, 1 in (5, 10] and 1 above the last bound", summary.Histogram)
+	}
+	if summary.Histogram[len(summary.Histogram)-1].UpperEUR != nil {
+		t.Fatal("last bucket has an upper bound")
+	}
+	if summary.Count != 4 || summary.MinEUR != 0.5 || summary.MaxEUR != 20000 {
+		t.Fatalf("count/min/max = %d/%v/%v, want 4/0.5/20000", summary.Count, summary.MinEUR, summary.MaxEUR)
+	}
+}
@@ -0,0 +1,177 @@
+package statistics
+
+import (
+	"sync"
+
+	"github.com/Bitstarz-eng/event-processing-challenge/internal/casino"
+)
+
+// Amounts with a distribution, named by their event type
+var DistributionMetrics = []string{
+	casino.BET,
+	casino.DEPOSIT,
+}
+
+// Upper bounds of the histogram buckets in EUR, the last bucket has no upper bound
+var HISTOGRAM_BOUNDS_EUR = []float64{1, 5, 10, 25, 50, 100, 250, 500, 1000, 5000, 10000}
+
+// Distribution keeps the quantile sketch and the histogram of the amounts in EUR
+type Distribution struct {
+	sketch    *DDSketch
+	histogram []int64 // Count of each bucket of HISTOGRAM_BOUNDS_EUR and of the last bucket
+}
+
+func NewDistribution() *Distribution {
+	return &Distribution{
+		sketch:    NewDDSketch(),
+		histogram: make([]int64, len(HISTOGRAM_BOUNDS_EUR)+1),
+	}
+}
+
+func (d *Distribution) Add(amountEUR float64) {
+	d.sketch.Add(amountEUR)
+
+	bucket := 0
+	for bucket < len(HISTOGRAM_BOUNDS_EUR) && amountEUR > HISTOGRAM_BOUNDS_EUR[bucket] {
+		bucket++
+	}
+	d.histogram[bucket]++
+}
+
+// DistributionSummary is the read-only view of a distribution, the percentiles are within 1% of the exact values
+type DistributionSummary struct {
+	Count     int64             `json:"count"`
+	SumEUR    float64           `json:"sum_eur"`
+	MinEUR    float64           `json:"min_eur"`
+	MaxEUR    float64           `json:"max_eur"`
+	MeanEUR   float64           `json:"mean_eur"`
+	P50EUR    float64           `json:"p50_eur"`
+	P90EUR    float64           `json:"p90_eur"`
+	P99EUR    float64           `json:"p99_eur"`
+	Histogram []HistogramBucket `json:"histogram"`
+}
+
+// HistogramBucket counts the amounts in (lower_eur, upper_eur], the last bucket has no upper bound
+type HistogramBucket struct {
+	LowerEUR float64  `json:"lower_eur"`
+	UpperEUR *float64 `json:"upper_eur"`
+	Count    int64    `json:"count"`
+}
+
+func (d *Distribution) Summary() DistributionSummary {
+	summary := DistributionSummary{
+		Count:     d.sketch.count,
+		SumEUR:    d.sketch.sum,
+		MinEUR:    d.sketch.min,
+		MaxEUR:    d.sketch.max,
+		P50EUR:    d.sketch.Quantile(0.5),
+		P90EUR:    d.sketch.Quantile(0.9),
+		P99EUR:    d.sketch.Quantile(0.99),
+		Histogram: make([]HistogramBucket, len(d.histogram)),
+	}
+	if summary.Count > 0 {
+		summary.MeanEUR = summary.SumEUR / float64(summary.Count)
+	}
+
+	for i, count := range d.histogram {
+		bucket := HistogramBucket{Count: count}
+		if i > 0 {
+			bucket.LowerEUR = HISTOGRAM_BOUNDS_EUR[i-1]
+		}
+		if i < len(HISTOGRAM_BOUNDS_EUR) {
+			upper := HISTOGRAM_BOUNDS_EUR[i]
+			bucket.UpperEUR = &upper
+		}
+		summary.Histogram[i] = bucket
+	}
+	return summary
+}
+
+// DistributionStats is the distribution of one metric overall, per game and per currency of the event
+type DistributionStats struct {
+	All         DistributionSummary            `json:"all"`
+	PerGame     map[int]DistributionSummary    `json:"per_game"`
+	PerCurrency map[string]DistributionSummary `json:"per_currency"`
+}
+
+type metricDistributions struct {
+	all        *Distribution
+	games      map[int]*Distribution
+	currencies map[string]*Distribution
+}
+
+func newMetricDistributions() *metricDistributions {
+	return &metricDistributions{
+		all:        NewDistribution(),
+		games:      make(map[int]*Distribution),
+		currencies: make(map[string]*Distribution),
+	}
+}
+
+// AmountDistributions keeps the distributions of the bet and deposit amounts, it is safe for concurrent events and reads
+type AmountDistributions struct {
+	mu      sync.Mutex
+	metrics map[string]*metricDistributions
+}
+
+func NewAmountDistributions() *AmountDistributions {
+	metrics := make(map[string]*metricDistributions, len(DistributionMetrics))
+	for _, metric := range DistributionMetrics {
+		metrics[metric] = newMetricDistributions()
+	}
+	return &AmountDistributions{
+		metrics: metrics,
+	}
+}
+
+// Record adds the EUR amount of the bet and deposit events, deposits have no game
+func (ad *AmountDistributions) Record(event *casino.Event) {
+	md, ok := ad.metrics[event.Type]
+	if !ok {
+		return
+	}
+	amountEUR := toEUR(int64(event.AmountEUR))
+
+	ad.mu.Lock()
+	defer ad.mu.Unlock()
+
+	md.all.Add(amountEUR)
+	if event.GameID != 0 {
+		game, ok := md.games[event.GameID]
+		if !ok {
+			game = NewDistribution()
+			md.games[event.GameID] = game
+		}
+		game.Add(amountEUR)
+	}
+	currency, ok := md.currencies[event.Currency]
+	if !ok {
+		currency = NewDistribution()
+		md.currencies[event.Currency] = currency
+	}
+	currency.Add(amountEUR)
+}
+
+// Stats returns the distribution of the metric, false if the metric is unknown
+func (ad *AmountDistributions) Stats(metric string) (*DistributionStats, bool) {
+	md, ok := ad.metrics[metric]
+	if !ok {
+		return nil, false
+	}
+
+	ad.mu.Lock()
+	defer ad.mu.Unlock()
+
+	stats := &DistributionStats{
+		All:         md.all.Summary(),
+		PerGame:     make(map[int]DistributionSummary, len(md.games)),
+		PerCurrency: make(map[string]DistributionSummary, len(md.currencies)),
+	}
+	for gameId, distribution := range md.games {
+		stats.PerGame[gameId] = distribution.Summary()
+	}
+	for currency, distribution := range md.currencies {
+		stats.PerCurrency[currency] = distribution.Summary()
+	}
+	return stats, true
+}
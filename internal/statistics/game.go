@@ -3,20 +3,46 @@ package statistics
 import (
 	"encoding/json"
 	"log"
+	"sort"
+	"sync"
 
 	"github.com/Bitstarz-eng/event-processing-challenge/internal/casino"
 )
 
-var (
-	mostPlayedGame StatisticCount
-	mostBettedGame StatisticAmount
-)
-
 type GameData struct {
+	// Serializes the updates of the game, the fields below are guarded by mu
+	mu sync.Mutex
+
 	Id                int                `json:"id"`
 	Name              string             `json:"name"`
 	GamePlayedCounter int                `json:"game_played_count"`
 	BetPerCurrency    map[string]float64 `json:"bet_per_currency"`
+	BetCount          int                `json:"bet_count"`
+	StakeAmount       int64              `json:"stake_amount"`  // EUR cents
+	PayoutAmount      int64              `json:"payout_amount"` // EUR cents
+}
+
+// GameStats is the game part of the /materialized API
+type GameStats struct {
+	MostPlayedGame *StatisticCount     `json:"most_played_game"`
+	MostBettedGame *StatisticAmountEUR `json:"most_betted_game"`
+	Games          []GameSummary       `json:"games"`
+	UniquePlayers  *UniqueStats        `json:"unique_players"`
+}
+
+// GameSummary is the read-only view of the game statistics served by the HTTP API
+type GameSummary struct {
+	Id                int                `json:"id"`
+	Name              string             `json:"name"`
+	GamePlayedCounter int                `json:"game_played_count"`
+	BetPerCurrency    map[string]float64 `json:"bet_per_currency"`
+	BetCount          int                `json:"bet_count"`
+	StakesEUR         float64            `json:"stakes_eur"`
+	PayoutsEUR        float64            `json:"payouts_eur"`
+	GGR               float64            `json:"ggr_eur"`
+	RTP               float64            `json:"rtp"`
+	AverageStakeEUR   float64            `json:"average_stake_eur"`
+	UniquePlayers     int64              `json:"unique_players"` // Approximate, see UniquePlayers
 }
 
 func NewGameData(id int) *GameData {
@@ -28,34 +54,202 @@ func NewGameData(id int) *GameData {
 	}
 }
 
-func CalculateMostPlayedGame(gameId, counter int) {
-	if counter > mostPlayedGame.Count {
-		mostPlayedGame = StatisticCount{
-			Id:    gameId,
-			Count: counter,
-		}
+func (gd *GameData) AddBet(currency string, amount, amountEUR int) {
+	gd.BetPerCurrency[currency] += float64(amount) * casino.SmallestUnit[currency]
+	gd.BetCount++
+	gd.StakeAmount += int64(amountEUR)
+}
+
+func (gd *GameData) AddPayout(amountEUR int) {
+	gd.PayoutAmount += int64(amountEUR)
+}
+
+// Summary returns a copy of the game statistics with the derived values:
+// GGR (stakes minus payouts), RTP (payouts divided by stakes) and the average stake.
+// The unique players are counted by the GameAggregator.
+func (gd *GameData) Summary() GameSummary {
+	summary := GameSummary{
+		Id:                gd.Id,
+		Name:              gd.Name,
+		GamePlayedCounter: gd.GamePlayedCounter,
+		BetPerCurrency:    make(map[string]float64, len(gd.BetPerCurrency)),
+		BetCount:          gd.BetCount,
+		StakesEUR:         toEUR(gd.StakeAmount),
+		PayoutsEUR:        toEUR(gd.PayoutAmount),
+		GGR:               toEUR(gd.StakeAmount - gd.PayoutAmount),
+	}
+	for currency, amount := range gd.BetPerCurrency {
+		summary.BetPerCurrency[currency] = amount
+	}
+	if gd.StakeAmount > 0 {
+		summary.RTP = float64(gd.PayoutAmount) / float64(gd.StakeAmount)
+	}
+	if gd.BetCount > 0 {
+		summary.AverageStakeEUR = toEUR(gd.StakeAmount) / float64(gd.BetCount)
 	}
+	return summary
+}
+
+// GameAggregator keeps the statistics of each game, the most played and betted games and the unique players.
+// Each GameSubscriber owns its aggregator, it is safe for concurrent events and reads.
+// The events of different games are handled in parallel, each game has its own lock.
+type GameAggregator struct {
+	mu    sync.RWMutex // Guards the games map
+	games map[int]*GameData
+
+	topMu          sync.Mutex      // Guards the most played and betted games
+	mostPlayedGame *StatisticCount // Count of the game_stop events
+	mostBettedGame StatisticAmount // Total stakes in EUR cents
+
+	// Distinct players of all events, not only the game events
+	Unique *UniquePlayers
 }
 
-func CalculateMostBettedGame(gameId, amount int) {
-	if amount > mostBettedGame.Amount {
-		mostBettedGame = StatisticAmount{
+func NewGameAggregator() *GameAggregator {
+	return &GameAggregator{
+		games:          make(map[int]*GameData),
+		mostPlayedGame: NewStatisticCount(),
+		Unique:         NewUniquePlayers(),
+	}
+}
+
+func (ga *GameAggregator) HandleEvent(event *casino.Event) {
+	ga.Unique.HandleEvent(event)
+
+	gameId := event.GameID
+	if gameId == 0 {
+		// Deposits, withdrawals and bonus credits are not related to a game
+		return
+	}
+
+	gd := ga.game(gameId)
+	gd.mu.Lock()
+	defer gd.mu.Unlock()
+
+	switch event.Type {
+	case casino.GAME_STOP:
+		gd.GamePlayedCounter++
+		ga.calculateMostPlayedGame(gameId, gd.GamePlayedCounter)
+	case casino.BET:
+		gd.AddBet(event.Currency, event.Amount, event.AmountEUR)
+		ga.calculateMostBettedGame(gameId, int(gd.StakeAmount))
+	case casino.WIN:
+		gd.AddPayout(event.AmountEUR)
+	default:
+		break
+	}
+}
+
+// Returns the statistics of the game, created with the first event of the game
+func (ga *GameAggregator) game(gameId int) *GameData {
+	ga.mu.RLock()
+	gd, ok := ga.games[gameId]
+	ga.mu.RUnlock()
+	if ok {
+		return gd
+	}
+
+	ga.mu.Lock()
+	defer ga.mu.Unlock()
+	if gd, ok = ga.games[gameId]; !ok {
+		gd = NewGameData(gameId)
+		ga.games[gameId] = gd
+	}
+	return gd
+}
+
+func (ga *GameAggregator) calculateMostPlayedGame(gameId, counter int) {
+	ga.topMu.Lock()
+	defer ga.topMu.Unlock()
+	if counter > ga.mostPlayedGame.Count {
+		ga.mostPlayedGame.SetValues(gameId, counter)
+	}
+}
+
+// The most betted game has the highest total stakes in EUR cents
+func (ga *GameAggregator) calculateMostBettedGame(gameId, amount int) {
+	ga.topMu.Lock()
+	defer ga.topMu.Unlock()
+	if amount > ga.mostBettedGame.Amount {
+		ga.mostBettedGame = StatisticAmount{
 			Id:     gameId,
 			Amount: amount,
 		}
 	}
 }
 
-func GetMostPlayedGame() StatisticCount {
-	return mostPlayedGame
+// Snapshot returns a copy of the most played game, the most betted game, the statistics of each game and the unique players
+func (ga *GameAggregator) Snapshot() *GameStats {
+	stats := &GameStats{
+		Games:         ga.Games(),
+		UniquePlayers: ga.Unique.Stats(),
+	}
+
+	ga.topMu.Lock()
+	defer ga.topMu.Unlock()
+	stats.MostPlayedGame = &StatisticCount{
+		Id:    ga.mostPlayedGame.Id,
+		Count: ga.mostPlayedGame.Count,
+	}
+	stats.MostBettedGame = &StatisticAmountEUR{
+		Id:        ga.mostBettedGame.Id,
+		AmountEUR: toEUR(int64(ga.mostBettedGame.Amount)),
+	}
+	return stats
 }
 
-func GetMostBettedGame() StatisticAmount {
-	return mostBettedGame
+// Games returns the summaries of all played games ordered by game ID
+func (ga *GameAggregator) Games() []GameSummary {
+	data := ga.gameData()
+	games := make([]GameSummary, 0, len(data))
+	for _, gd := range data {
+		games = append(games, ga.summary(gd))
+	}
+	sort.Slice(games, func(i, j int) bool {
+		return games[i].Id < games[j].Id
+	})
+	return games
+}
+
+// Game returns the summary of the game, false if the game is unknown
+func (ga *GameAggregator) Game(gameId int) (GameSummary, bool) {
+	if _, ok := casino.Games[gameId]; !ok {
+		return GameSummary{}, false
+	}
+
+	ga.mu.RLock()
+	gd, ok := ga.games[gameId]
+	ga.mu.RUnlock()
+
+	if ok {
+		return ga.summary(gd), true
+	}
+	return NewGameData(gameId).Summary(), true
+}
+
+// Returns the statistics of all played games, the map is not locked while the games are read
+func (ga *GameAggregator) gameData() []*GameData {
+	ga.mu.RLock()
+	defer ga.mu.RUnlock()
+
+	games := make([]*GameData, 0, len(ga.games))
+	for _, gd := range ga.games {
+		games = append(games, gd)
+	}
+	return games
+}
+
+func (ga *GameAggregator) summary(gd *GameData) GameSummary {
+	gd.mu.Lock()
+	summary := gd.Summary()
+	gd.mu.Unlock()
+
+	summary.UniquePlayers = ga.Unique.Game(gd.Id)
+	return summary
 }
 
 func (gd *GameData) String() string {
-	gameData, err := json.MarshalIndent(gd, "", "  ")
+	gameData, err := json.MarshalIndent(gd.Summary(), "", "  ")
 	if err != nil {
 		log.Println("Error marshaling GameData to JSON:", err)
 	}
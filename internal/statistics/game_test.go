@@ -0,0 +1,109 @@
+package statistics
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Bitstarz-eng/event-processing-challenge/internal/casino"
+)
+
+func gameStop(playerID, gameID int) *casino.Event {
+	return &casino.Event{
+		ID:        1,
+		PlayerID:  playerID,
+		GameID:    gameID,
+		Type:      casino.GAME_STOP,
+		CreatedAt: time.Now(),
+	}
+}
+
+func TestGameAggregatorsAreIndependent(t *testing.T) {
+	first := NewGameAggregator()
+	second := NewGameAggregator()
+
+	first.HandleEvent(gameStop(10, 100))
+
+	if games := second.Games(); len(games) != 0 {
+		t.Fatalf("second aggregator has %d games, want 0", len(games))
+	}
+	if stats := second.Snapshot(); stats.MostPlayedGame.Id != 0 {
+		t.Fatalf("second aggregator most played game = %d, want none", stats.MostPlayedGame.Id)
+	}
+	if stats := first.Snapshot(); stats.MostPlayedGame.Id != 100 || stats.MostPlayedGame.Count != 1 {
+		t.Fatalf("first aggregator most played game = %d/%d, want 100/1", stats.MostPlayedGame.Id, stats.MostPlayedGame.Count)
+	}
+}
+
+func TestGameAggregatorConcurrentEventsAndReads(t *testing.T) {
+	const (
+		writers        = 8
+		eventsPerGame  = 300
+		readers        = 4
+		amount         = 100
+		mostBettedGame = 101
+	)
+
+	ga := NewGameAggregator()
+
+	done := make(chan struct{})
+
+	var readersWg sync.WaitGroup
+	for i := 0; i < readers; i++ {
+		readersWg.Add(1)
+		go func() {
+			defer readersWg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				ga.Snapshot()
+				ga.Games()
+				ga.Game(100)
+
+				// Let the writers run between the reads, also with a single CPU
+				runtime.Gosched()
+			}
+		}()
+	}
+
+	// Game 100 is played most, game 101 gets the highest stakes
+	var writersWg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		writersWg.Add(1)
+		go func(playerID int) {
+			defer writersWg.Done()
+			for i := 0; i < eventsPerGame; i++ {
+				ga.HandleEvent(gameStop(playerID, 100))
+				ga.HandleEvent(bet(i+1, playerID, amount, false))
+
+				event := bet(i+1, playerID, 2*amount, false)
+				event.GameID = mostBettedGame
+				ga.HandleEvent(event)
+			}
+		}(w + 1)
+	}
+
+	writersWg.Wait()
+	close(done)
+	readersWg.Wait()
+
+	stats := ga.Snapshot()
+	if stats.MostPlayedGame.Id != 100 || stats.MostPlayedGame.Count != writers*eventsPerGame {
+		t.Fatalf("most played game = %d/%d, want 100/%d", stats.MostPlayedGame.Id, stats.MostPlayedGame.Count, writers*eventsPerGame)
+	}
+	if stats.MostBettedGame.Id != mostBettedGame || stats.MostBettedGame.AmountEUR != toEUR(writers*eventsPerGame*2*amount) {
+		t.Fatalf("most betted game = %d/%v", stats.MostBettedGame.Id, stats.MostBettedGame.AmountEUR)
+	}
+
+	game, ok := ga.Game(100)
+	if !ok {
+		t.Fatal("game 100 is unknown")
+	}
+	if game.BetCount != writers*eventsPerGame || game.UniquePlayers != writers {
+		t.Fatalf("game 100 bets/players = %d/%d, want %d/%d", game.BetCount, game.UniquePlayers, writers*eventsPerGame, writers)
+	}
+}
@@ -0,0 +1,87 @@
+package statistics
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+)
+
+// Precision of the in-memory HyperLogLog: 2^12 registers of one byte, the standard error is 1.04/sqrt(4096) ≈ 1.6%
+const (
+	HLL_PRECISION = 12
+	HLL_REGISTERS = 1 << HLL_PRECISION
+)
+
+// HyperLogLog estimates the number of distinct players, it is not safe for concurrent use
+type HyperLogLog struct {
+	registers []uint8
+}
+
+func NewHyperLogLog() *HyperLogLog {
+	return &HyperLogLog{
+		registers: make([]uint8, HLL_REGISTERS),
+	}
+}
+
+// Add counts the player, the first bits of the hash select the register and the rest keep the longest run of zeros
+func (h *HyperLogLog) Add(playerID int) {
+	hash := hashPlayer(playerID)
+	register := hash >> (64 - HLL_PRECISION)
+	rank := uint8(bits.LeadingZeros64(hash<<HLL_PRECISION|1<<(HLL_PRECISION-1)) + 1)
+	if rank > h.registers[register] {
+		h.registers[register] = rank
+	}
+}
+
+// Merge adds the players of the other sketch, the result estimates the union
+func (h *HyperLogLog) Merge(other *HyperLogLog) {
+	for i, rank := range other.registers {
+		if rank > h.registers[i] {
+			h.registers[i] = rank
+		}
+	}
+}
+
+// Count returns the estimated number of distinct players, small counts use linear counting
+func (h *HyperLogLog) Count() int64 {
+	sum := 0.0
+	zeros := 0
+	for _, rank := range h.registers {
+		sum += 1 / float64(uint64(1)<<rank)
+		if rank == 0 {
+			zeros++
+		}
+	}
+
+	m := float64(HLL_REGISTERS)
+	estimate := 0.7213 / (1 + 1.079/m) * m * m / sum
+	if estimate <= 2.5*m && zeros > 0 {
+		estimate = m * math.Log(m/float64(zeros))
+	}
+	return int64(math.Round(estimate))
+}
+
+// Bytes returns a copy of the registers, used by the snapshots
+func (h *HyperLogLog) Bytes() []byte {
+	registers := make([]byte, len(h.registers))
+	copy(registers, h.registers)
+	return registers
+}
+
+// HyperLogLogFromBytes loads the registers of Bytes
+func HyperLogLogFromBytes(registers []byte) (*HyperLogLog, error) {
+	if len(registers) != HLL_REGISTERS {
+		return nil, fmt.Errorf("HyperLogLog has %d registers, want %d", len(registers), HLL_REGISTERS)
+	}
+	h := NewHyperLogLog()
+	copy(h.registers, registers)
+	return h, nil
+}
+
+// The player IDs are sequential, the SplitMix64 finalizer spreads them over all bits
+func hashPlayer(playerID int) uint64 {
+	x := uint64(playerID) + 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
@@ -0,0 +1,66 @@
+package statistics
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/Bitstarz-eng/event-processing-challenge/internal/casino"
+)
+
+func TestHyperLogLogEstimate(t *testing.T) {
+	for _, players := range []int{1, 10, 1000, 100000} {
+		h := NewHyperLogLog()
+		for id := 1; id <= players; id++ {
+			h.Add(id)
+			h.Add(id) // Duplicates are not counted
+		}
+
+		// Three standard errors
+		if diff := math.Abs(float64(h.Count()-int64(players))) / float64(players); diff > 0.05 {
+			t.Fatalf("count of %d players = %d, error %.3f", players, h.Count(), diff)
+		}
+	}
+}
+
+func TestHyperLogLogMerge(t *testing.T) {
+	first := NewHyperLogLog()
+	second := NewHyperLogLog()
+	for id := 1; id <= 300; id++ {
+		first.Add(id)
+		second.Add(id + 200)
+	}
+
+	first.Merge(second)
+	if count := first.Count(); count < 490 || count > 510 {
+		t.Fatalf("count of the union = %d, want about 500", count)
+	}
+}
+
+func TestUniquePlayersWindows(t *testing.T) {
+	// The windows end at the watermark, without the allowed lateness it is the latest event
+	t.Setenv("ALLOWED_LATENESS", "0s")
+	up := NewUniquePlayers()
+	now := time.Now()
+
+	// Two players two hours ago and one player now, the player 1 plays in both
+	for _, event := range []casino.Event{
+		{PlayerID: 1, GameID: 100, Currency: "EUR", CreatedAt: now.Add(-2 * time.Hour)},
+		{PlayerID: 2, GameID: 101, Currency: "USD", CreatedAt: now.Add(-2 * time.Hour)},
+		{PlayerID: 1, GameID: 100, Currency: "EUR", CreatedAt: now},
+	} {
+		event := event
+		up.HandleEvent(&event)
+	}
+
+	stats := up.Stats()
+	if stats.Players != 2 || stats.PerCurrency["EUR"] != 1 || stats.PerCurrency["USD"] != 1 {
+		t.Fatalf("players/EUR/USD = %d/%d/%d, want 2/1/1", stats.Players, stats.PerCurrency["EUR"], stats.PerCurrency["USD"])
+	}
+	if stats.PerWindow[WINDOW_1H] != 1 || stats.PerWindow[WINDOW_24H] != 2 {
+		t.Fatalf("players in 1h/24h = %d/%d, want 1/2", stats.PerWindow[WINDOW_1H], stats.PerWindow[WINDOW_24H])
+	}
+	if players := up.Game(100); players != 1 {
+		t.Fatalf("players of game 100 = %d, want 1", players)
+	}
+}
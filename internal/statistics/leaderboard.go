@@ -0,0 +1,149 @@
+package statistics
+
+import (
+	"math/rand"
+	"sync"
+)
+
+// Leaderboard metrics, amounts are in EUR cents
+const (
+	LEADERBOARD_BET_COUNT   = "bet_count"
+	LEADERBOARD_BET_AMOUNT  = "bet_amount"
+	LEADERBOARD_DEPOSIT_SUM = "deposit_sum"
+	LEADERBOARD_WIN_COUNT   = "win_count"
+	LEADERBOARD_NET_RESULT  = "net_result" // Payouts minus stakes of the player
+)
+
+var LeaderboardMetrics = []string{
+	LEADERBOARD_BET_COUNT,
+	LEADERBOARD_BET_AMOUNT,
+	LEADERBOARD_DEPOSIT_SUM,
+	LEADERBOARD_WIN_COUNT,
+	LEADERBOARD_NET_RESULT,
+}
+
+type LeaderboardEntry struct {
+	Rank     int   `json:"rank"`
+	PlayerID int   `json:"player_id"`
+	Value    int64 `json:"value"`
+}
+
+// Levels of the leaderboard skiplist, each level links about a quarter of the nodes of the level below
+const (
+	LEADERBOARD_MAX_LEVEL   = 24
+	LEADERBOARD_LEVEL_RATIO = 4
+)
+
+type leaderboardNode struct {
+	entry LeaderboardEntry
+	next  []*leaderboardNode
+}
+
+// Leaderboard keeps all players ordered by value (highest first), ties are broken by the lowest player ID.
+// The players are kept in a skiplist, so an update repositions the player in O(log n) for n players and Top(limit) reads the first limit nodes.
+type Leaderboard struct {
+	mu     sync.RWMutex
+	head   *leaderboardNode
+	level  int                      // Levels in use
+	nodes  map[int]*leaderboardNode // Player ID -> node
+	random *rand.Rand
+}
+
+func NewLeaderboard() *Leaderboard {
+	return &Leaderboard{
+		head:   &leaderboardNode{next: make([]*leaderboardNode, LEADERBOARD_MAX_LEVEL)},
+		level:  1,
+		nodes:  make(map[int]*leaderboardNode),
+		random: rand.New(rand.NewSource(1)),
+	}
+}
+
+// Update sets the value of the player and moves the player to its new position
+func (lb *Leaderboard) Update(playerID int, value int64) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	node, ok := lb.nodes[playerID]
+	if ok {
+		if node.entry.Value == value {
+			return
+		}
+		lb.remove(node)
+		node.entry.Value = value
+	} else {
+		node = &leaderboardNode{
+			entry: LeaderboardEntry{PlayerID: playerID, Value: value},
+			next:  make([]*leaderboardNode, lb.randomLevel()),
+		}
+		lb.nodes[playerID] = node
+	}
+	lb.insert(node)
+}
+
+// Top returns a copy of the first limit entries with their rank
+func (lb *Leaderboard) Top(limit int) []LeaderboardEntry {
+	lb.mu.RLock()
+	defer lb.mu.RUnlock()
+
+	if limit > len(lb.nodes) {
+		limit = len(lb.nodes)
+	}
+	top := make([]LeaderboardEntry, 0, limit)
+	for node := lb.head.next[0]; node != nil && len(top) < limit; node = node.next[0] {
+		entry := node.entry
+		entry.Rank = len(top) + 1
+		top = append(top, entry)
+	}
+	return top
+}
+
+// Returns the last node of each level ordered before the entry
+func (lb *Leaderboard) predecessors(entry *LeaderboardEntry) []*leaderboardNode {
+	previous := make([]*leaderboardNode, LEADERBOARD_MAX_LEVEL)
+	node := lb.head
+	for level := lb.level - 1; level >= 0; level-- {
+		for node.next[level] != nil && rankedBefore(&node.next[level].entry, entry) {
+			node = node.next[level]
+		}
+		previous[level] = node
+	}
+	return previous
+}
+
+func (lb *Leaderboard) insert(node *leaderboardNode) {
+	for lb.level < len(node.next) {
+		lb.level++
+	}
+	previous := lb.predecessors(&node.entry)
+	for level := range node.next {
+		node.next[level] = previous[level].next[level]
+		previous[level].next[level] = node
+	}
+}
+
+func (lb *Leaderboard) remove(node *leaderboardNode) {
+	previous := lb.predecessors(&node.entry)
+	for level := range node.next {
+		if previous[level].next[level] == node {
+			previous[level].next[level] = node.next[level]
+		}
+	}
+	for lb.level > 1 && lb.head.next[lb.level-1] == nil {
+		lb.level--
+	}
+}
+
+func (lb *Leaderboard) randomLevel() int {
+	level := 1
+	for level < LEADERBOARD_MAX_LEVEL && lb.random.Intn(LEADERBOARD_LEVEL_RATIO) == 0 {
+		level++
+	}
+	return level
+}
+
+func rankedBefore(a, b *LeaderboardEntry) bool {
+	if a.Value != b.Value {
+		return a.Value > b.Value
+	}
+	return a.PlayerID < b.PlayerID
+}
@@ -0,0 +1,54 @@
+package statistics
+
+import (
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestLeaderboardOrdersLikeSort(t *testing.T) {
+	lb := NewLeaderboard()
+	values := make(map[int]int64)
+	random := rand.New(rand.NewSource(42))
+	for i := 0; i < 5000; i++ {
+		playerID := random.Intn(300)
+		// Small values produce ties, broken by the player ID
+		value := random.Int63n(50) - 10
+		values[playerID] = value
+		lb.Update(playerID, value)
+	}
+
+	want := make([]LeaderboardEntry, 0, len(values))
+	for playerID, value := range values {
+		want = append(want, LeaderboardEntry{PlayerID: playerID, Value: value})
+	}
+	sort.Slice(want, func(i, j int) bool { return rankedBefore(&want[i], &want[j]) })
+	for i := range want {
+		want[i].Rank = i + 1
+	}
+
+	if got := lb.Top(len(values) + 10); !reflect.DeepEqual(got, want) {
+		t.Fatalf("leaderboard = %v, want %v", got, want)
+	}
+	if got := lb.Top(3); !reflect.DeepEqual(got, want[:3]) {
+		t.Fatalf("top 3 = %v, want %v", got, want[:3])
+	}
+}
+
+func BenchmarkLeaderboardUpdate(b *testing.B) {
+	const players = 100000
+	lb := NewLeaderboard()
+	amounts := make([]int64, players)
+	for playerID := range amounts {
+		lb.Update(playerID, 0)
+	}
+
+	random := rand.New(rand.NewSource(1))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		playerID := random.Intn(players)
+		amounts[playerID] += random.Int63n(100000)
+		lb.Update(playerID, amounts[playerID])
+	}
+}
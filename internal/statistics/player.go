@@ -3,75 +3,306 @@ package statistics
 import (
 	"encoding/json"
 	"log"
+	"sync"
 	"sync/atomic"
+	"time"
+
+	"github.com/Bitstarz-eng/event-processing-challenge/internal/casino"
 )
 
 type PlayerData struct {
-	BetCount      atomic.Int64 `json:"bet_count"`
-	BetAmount     atomic.Int64 `json:"bet_amount"`
-	DepositCount  atomic.Int64 `json:"deposit_count"`
-	DepositAmount atomic.Int64 `json:"deposit_amount"`
-	WonCount      atomic.Int64 `json:"won_count"`
+	// Serializes the updates of the player, so the leaderboards get the values in order
+	mu sync.Mutex
+
+	BetCount         atomic.Int64 `json:"bet_count"`
+	BetAmount        atomic.Int64 `json:"bet_amount"`
+	DepositCount     atomic.Int64 `json:"deposit_count"`
+	DepositAmount    atomic.Int64 `json:"deposit_amount"`
+	WonCount         atomic.Int64 `json:"won_count"`
+	WinAmount        atomic.Int64 `json:"win_amount"`
+	WithdrawalCount  atomic.Int64 `json:"withdrawal_count"`
+	WithdrawalAmount atomic.Int64 `json:"withdrawal_amount"`
+	BonusCount       atomic.Int64 `json:"bonus_count"`
+	BonusAmount      atomic.Int64 `json:"bonus_amount"`
+	LastSeen         atomic.Int64 `json:"last_seen"` // Unix nanoseconds of the latest created_at
+
+	// Events per game and per currency, guarded by mu
+	games      map[int]int64
+	currencies map[string]int64
 }
 
 func NewPlayerData() *PlayerData {
-	return &PlayerData{}
+	return &PlayerData{
+		games:      make(map[int]int64),
+		currencies: make(map[string]int64),
+	}
+}
+
+// PlayerSnapshot is a copy of the player statistics, amounts are in EUR cents
+type PlayerSnapshot struct {
+	BetCount         int64     `json:"bet_count"`
+	BetAmount        int64     `json:"bet_amount"`
+	DepositCount     int64     `json:"deposit_count"`
+	DepositAmount    int64     `json:"deposit_amount"`
+	WinCount         int64     `json:"win_count"`
+	WinAmount        int64     `json:"win_amount"`
+	WithdrawalCount  int64     `json:"withdrawal_count"`
+	WithdrawalAmount int64     `json:"withdrawal_amount"`
+	BonusCount       int64     `json:"bonus_count"`
+	BonusAmount      int64     `json:"bonus_amount"`
+	LastSeen         time.Time `json:"last_seen"`
+}
+
+// Snapshot returns a copy of the player statistics
+func (pd *PlayerData) Snapshot() PlayerSnapshot {
+	snapshot := PlayerSnapshot{
+		BetCount:         pd.BetCount.Load(),
+		BetAmount:        pd.BetAmount.Load(),
+		DepositCount:     pd.DepositCount.Load(),
+		DepositAmount:    pd.DepositAmount.Load(),
+		WinCount:         pd.WonCount.Load(),
+		WinAmount:        pd.WinAmount.Load(),
+		WithdrawalCount:  pd.WithdrawalCount.Load(),
+		WithdrawalAmount: pd.WithdrawalAmount.Load(),
+		BonusCount:       pd.BonusCount.Load(),
+		BonusAmount:      pd.BonusAmount.Load(),
+	}
+	if lastSeen := pd.LastSeen.Load(); lastSeen != 0 {
+		snapshot.LastSeen = time.Unix(0, lastSeen).UTC()
+	}
+	return snapshot
 }
 
-type PlayerStats struct {
-	TopPlayerBet     *StatisticCount `json:"top_player_bet"`
-	TopPlayerDeposit *StatisticCount `json:"top_player_deposit"`
-	TopPlayerWin     *StatisticCount `json:"top_player_win"`
+// NetResult returns the payouts minus the stakes of the player in EUR cents
+func (pd *PlayerData) NetResult() int64 {
+	return pd.WinAmount.Load() - pd.BetAmount.Load()
 }
 
-var playerStats PlayerStats = PlayerStats{
-	TopPlayerBet:     NewStatisticCount(),
-	TopPlayerDeposit: NewStatisticCount(),
-	TopPlayerWin:     NewStatisticCount(),
+// PlayerAggregator keeps the statistics of each player, the leaderboards and the totals of all players.
+// Each PlayerSubscriber owns its aggregator, it is safe for concurrent events and reads.
+type PlayerAggregator struct {
+	mu      sync.RWMutex
+	players map[int]*PlayerData
+
+	// Lifetime leaderboard of each metric in LeaderboardMetrics
+	Leaderboards map[string]*Leaderboard
+
+	// Metrics of each player per minute, for the time windowed leaderboards
+	Windows *PlayerWindows
+
+	// Distributions of the bet and deposit amounts of all players
+	Distributions *AmountDistributions
+
+	// Totals of all players in EUR cents
+	TotalBetAmount        atomic.Int64
+	TotalWinAmount        atomic.Int64
+	TotalDepositAmount    atomic.Int64
+	TotalWithdrawalAmount atomic.Int64
+	TotalBonusAmount      atomic.Int64
+}
+
+func NewPlayerAggregator() *PlayerAggregator {
+	leaderboards := make(map[string]*Leaderboard, len(LeaderboardMetrics))
+	for _, metric := range LeaderboardMetrics {
+		leaderboards[metric] = NewLeaderboard()
+	}
+
+	return &PlayerAggregator{
+		players:       make(map[int]*PlayerData),
+		Leaderboards:  leaderboards,
+		Windows:       NewPlayerWindows(loadTimezone(), NewWatermark()),
+		Distributions: NewAmountDistributions(),
+	}
 }
 
-func (pd *PlayerData) CalculateBetValues(id, amount int) {
+func (pa *PlayerAggregator) HandleEvent(event *casino.Event) {
+	id := event.PlayerID
+	pd := pa.player(id)
+
+	pd.mu.Lock()
+	defer pd.mu.Unlock()
+
+	switch event.Type {
+	case casino.BET:
+		pa.calculateBetValues(id, pd, event.AmountEUR)
+	case casino.DEPOSIT:
+		pa.calculateDepositValues(id, pd, event.AmountEUR)
+	case casino.WIN:
+		pa.calculatePayoutValues(id, pd, event.AmountEUR)
+	case casino.WITHDRAWAL:
+		pa.calculateWithdrawalValues(pd, event.AmountEUR)
+	case casino.BONUS_CREDIT:
+		pa.calculateBonusValues(pd, event.AmountEUR)
+	default:
+		break
+	}
+
+	if event.HasWon {
+		pa.calculateWonValues(id, pd)
+	}
+
+	pd.addActivity(event)
+
+	pa.Windows.Record(event)
+	pa.Distributions.Record(event)
+}
+
+// Returns the statistics of the player, created with the first event of the player
+func (pa *PlayerAggregator) player(id int) *PlayerData {
+	pa.mu.RLock()
+	pd, ok := pa.players[id]
+	pa.mu.RUnlock()
+	if ok {
+		return pd
+	}
+
+	pa.mu.Lock()
+	defer pa.mu.Unlock()
+	if pd, ok = pa.players[id]; !ok {
+		pd = NewPlayerData()
+		pa.players[id] = pd
+	}
+	return pd
+}
+
+// Player returns a copy of the player statistics, false if the player has no events
+func (pa *PlayerAggregator) Player(id int) (PlayerSnapshot, bool) {
+	pa.mu.RLock()
+	pd, ok := pa.players[id]
+	pa.mu.RUnlock()
+	if !ok {
+		return PlayerSnapshot{}, false
+	}
+	return pd.Snapshot(), true
+}
+
+// Players returns a copy of the statistics of all players
+func (pa *PlayerAggregator) Players() map[int]PlayerSnapshot {
+	pa.mu.RLock()
+	defer pa.mu.RUnlock()
+
+	players := make(map[int]PlayerSnapshot, len(pa.players))
+	for id, pd := range pa.players {
+		players[id] = pd.Snapshot()
+	}
+	return players
+}
+
+// TopPlayerBet returns the player with the most bets in the window
+func (pa *PlayerAggregator) TopPlayerBet(window string) *StatisticCount {
+	return pa.TopPlayer(LEADERBOARD_BET_COUNT, window)
+}
+
+// TopPlayerDeposit returns the player with the highest deposits in EUR cents in the window
+func (pa *PlayerAggregator) TopPlayerDeposit(window string) *StatisticCount {
+	return pa.TopPlayer(LEADERBOARD_DEPOSIT_SUM, window)
+}
+
+// TopPlayerWin returns the player with the most won bets in the window
+func (pa *PlayerAggregator) TopPlayerWin(window string) *StatisticCount {
+	return pa.TopPlayer(LEADERBOARD_WIN_COUNT, window)
+}
+
+// TopPlayer returns the leading player of the metric in the window, empty if there are no players
+func (pa *PlayerAggregator) TopPlayer(metric, window string) *StatisticCount {
+	first := NewStatisticCount()
+	if top := pa.Top(metric, window, 1); len(top) > 0 {
+		first.SetValues(top[0].PlayerID, int(top[0].Value))
+	}
+	return first
+}
+
+// Top returns the first limit players of the metric in the window, the metric and the window must be known
+func (pa *PlayerAggregator) Top(metric, window string, limit int) []LeaderboardEntry {
+	if window == WINDOW_ALL {
+		return pa.Leaderboards[metric].Top(limit)
+	}
+	return pa.Windows.Top(metric, window, limit)
+}
+
+// IsMetric returns true if the metric has a leaderboard
+func (pa *PlayerAggregator) IsMetric(metric string) bool {
+	_, ok := pa.Leaderboards[metric]
+	return ok
+}
+
+// GGR returns the gross gaming revenue in EUR: stakes minus payouts
+func (pa *PlayerAggregator) GGR() float64 {
+	return toEUR(pa.TotalBetAmount.Load() - pa.TotalWinAmount.Load())
+}
+
+// NetDeposits returns the deposits minus the withdrawals in EUR
+func (pa *PlayerAggregator) NetDeposits() float64 {
+	return toEUR(pa.TotalDepositAmount.Load() - pa.TotalWithdrawalAmount.Load())
+}
+
+// Convert EUR cents to EUR
+func toEUR(cents int64) float64 {
+	return float64(cents) * casino.SmallestUnit["EUR"]
+}
+
+func (pa *PlayerAggregator) calculateBetValues(id int, pd *PlayerData, amount int) {
 	pd.BetCount.Add(1)
 	pd.BetAmount.Add(int64(amount))
+	pa.TotalBetAmount.Add(int64(amount))
 
 	// Player statistic update
-	if pd.BetCount.Load() > int64(playerStats.TopPlayerBet.Count) {
-		playerStats.TopPlayerBet.SetValues(id, int(pd.BetCount.Load()))
-	}
+	pa.Leaderboards[LEADERBOARD_BET_COUNT].Update(id, pd.BetCount.Load())
+	pa.Leaderboards[LEADERBOARD_BET_AMOUNT].Update(id, pd.BetAmount.Load())
+	pa.Leaderboards[LEADERBOARD_NET_RESULT].Update(id, pd.NetResult())
 }
 
-func (pd *PlayerData) CalculateDepositValues(id, amount int) {
+func (pa *PlayerAggregator) calculateDepositValues(id int, pd *PlayerData, amount int) {
 	pd.DepositCount.Add(1)
 	pd.DepositAmount.Add(int64(amount))
+	pa.TotalDepositAmount.Add(int64(amount))
 
 	// Player statistic update
-	if pd.DepositAmount.Load() > int64(playerStats.TopPlayerDeposit.Count) {
-		playerStats.TopPlayerDeposit.SetValues(id, int(pd.DepositAmount.Load()))
-	}
+	pa.Leaderboards[LEADERBOARD_DEPOSIT_SUM].Update(id, pd.DepositAmount.Load())
 }
 
-func (pd *PlayerData) CalculateWonValues(id int) {
+func (pa *PlayerAggregator) calculateWonValues(id int, pd *PlayerData) {
 	pd.WonCount.Add(1)
 
 	// Player statistic update
-	if pd.WonCount.Load() > int64(playerStats.TopPlayerWin.Count) {
-		playerStats.TopPlayerWin.SetValues(id, int(pd.WonCount.Load()))
-	}
+	pa.Leaderboards[LEADERBOARD_WIN_COUNT].Update(id, pd.WonCount.Load())
+}
+
+func (pa *PlayerAggregator) calculatePayoutValues(id int, pd *PlayerData, amount int) {
+	pd.WinAmount.Add(int64(amount))
+	pa.TotalWinAmount.Add(int64(amount))
+
+	// Player statistic update
+	pa.Leaderboards[LEADERBOARD_NET_RESULT].Update(id, pd.NetResult())
 }
 
-func GetStats() interface{} {
-	return &playerStats
+func (pa *PlayerAggregator) calculateWithdrawalValues(pd *PlayerData, amount int) {
+	pd.WithdrawalCount.Add(1)
+	pd.WithdrawalAmount.Add(int64(amount))
+	pa.TotalWithdrawalAmount.Add(int64(amount))
 }
 
-func (pd *PlayerData) String() string {
-	response := make(map[string]interface{})
-	response["bet_count"] = pd.BetCount.Load()
-	response["bet_amount"] = pd.BetAmount.Load()
-	response["deposit_count"] = pd.DepositCount.Load()
-	response["deposit_amount"] = pd.DepositAmount.Load()
-	response["win_count"] = pd.WonCount.Load()
+// Count the game and the currency of the event and keep the latest event time, the caller holds pd.mu
+func (pd *PlayerData) addActivity(event *casino.Event) {
+	if event.GameID != 0 {
+		pd.games[event.GameID]++
+	}
+	if event.Currency != "" {
+		pd.currencies[event.Currency]++
+	}
+	if createdAt := event.CreatedAt.UnixNano(); createdAt > pd.LastSeen.Load() {
+		pd.LastSeen.Store(createdAt)
+	}
+}
+
+func (pa *PlayerAggregator) calculateBonusValues(pd *PlayerData, amount int) {
+	pd.BonusCount.Add(1)
+	pd.BonusAmount.Add(int64(amount))
+	pa.TotalBonusAmount.Add(int64(amount))
+}
 
-	playerData, err := json.MarshalIndent(response, "", "  ")
+func (ps PlayerSnapshot) String() string {
+	playerData, err := json.MarshalIndent(ps, "", "  ")
 	if err != nil {
 		log.Println("Error marshaling Event to JSON:", err)
 	}
@@ -0,0 +1,145 @@
+package statistics
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Bitstarz-eng/event-processing-challenge/internal/casino"
+)
+
+func bet(id, playerID, amount int, hasWon bool) *casino.Event {
+	return &casino.Event{
+		ID:        id,
+		PlayerID:  playerID,
+		GameID:    100,
+		Type:      casino.BET,
+		Currency:  "EUR",
+		Amount:    amount,
+		AmountEUR: amount,
+		HasWon:    hasWon,
+		CreatedAt: time.Now(),
+	}
+}
+
+func TestPlayerAggregatorsAreIndependent(t *testing.T) {
+	first := NewPlayerAggregator()
+	second := NewPlayerAggregator()
+
+	first.HandleEvent(bet(1, 10, 500, true))
+
+	if _, ok := second.Player(10); ok {
+		t.Fatal("second aggregator has the player of the first aggregator")
+	}
+	if top := second.TopPlayerBet(WINDOW_ALL); top.Id != 0 || top.Count != 0 {
+		t.Fatalf("second aggregator top player = %d/%d, want empty", top.Id, top.Count)
+	}
+	if top := first.TopPlayerBet(WINDOW_ALL); top.Id != 10 || top.Count != 1 {
+		t.Fatalf("first aggregator top player = %d/%d, want 10/1", top.Id, top.Count)
+	}
+}
+
+func TestPlayerAggregatorConcurrentEventsAndReads(t *testing.T) {
+	const (
+		players         = 8
+		betsPerPlayer   = 500
+		readers         = 4
+		amount          = 100
+		expectedBetting = players * betsPerPlayer * amount
+	)
+
+	pa := NewPlayerAggregator()
+
+	done := make(chan struct{})
+
+	var readersWg sync.WaitGroup
+	for i := 0; i < readers; i++ {
+		readersWg.Add(1)
+		go func() {
+			defer readersWg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				pa.TopPlayerBet(WINDOW_ALL)
+				pa.TopPlayerWin(WINDOW_5M)
+				pa.Top(LEADERBOARD_NET_RESULT, WINDOW_1H, 3)
+				pa.Players()
+				pa.GGR()
+
+				// Let the writers run between the reads, also with a single CPU
+				runtime.Gosched()
+			}
+		}()
+	}
+
+	var writersWg sync.WaitGroup
+	for p := 1; p <= players; p++ {
+		writersWg.Add(1)
+		go func(playerID int) {
+			defer writersWg.Done()
+			for i := 0; i < betsPerPlayer; i++ {
+				pa.HandleEvent(bet(playerID*betsPerPlayer+i, playerID, amount, i%2 == 0))
+			}
+		}(p)
+	}
+
+	writersWg.Wait()
+	close(done)
+	readersWg.Wait()
+
+	if got := pa.TotalBetAmount.Load(); got != expectedBetting {
+		t.Fatalf("total bet amount = %d, want %d", got, expectedBetting)
+	}
+	for p := 1; p <= players; p++ {
+		player, ok := pa.Player(p)
+		if !ok {
+			t.Fatalf("player %d is missing", p)
+		}
+		if player.BetCount != betsPerPlayer || player.WinCount != betsPerPlayer/2 {
+			t.Fatalf("player %d bets/wins = %d/%d, want %d/%d", p, player.BetCount, player.WinCount, betsPerPlayer, betsPerPlayer/2)
+		}
+	}
+
+	// All players are tied, the lowest player ID is first
+	top := pa.Top(LEADERBOARD_BET_COUNT, WINDOW_ALL, players)
+	for i, entry := range top {
+		if entry.PlayerID != i+1 || entry.Value != betsPerPlayer || entry.Rank != i+1 {
+			t.Fatalf("leaderboard entry %d = %+v", i, entry)
+		}
+	}
+}
+
+func TestPlayerAggregatorPayouts(t *testing.T) {
+	pa := NewPlayerAggregator()
+	now := time.Now()
+	for _, event := range []*casino.Event{
+		{ID: 1, PlayerID: 10, Type: casino.DEPOSIT, Currency: "EUR", Amount: 5000, AmountEUR: 5000, CreatedAt: now},
+		bet(2, 10, 1000, true),
+		{ID: 3, PlayerID: 10, GameID: 100, Type: casino.WIN, BetID: 2, Currency: "EUR", Amount: 2000, AmountEUR: 2000, CreatedAt: now},
+		bet(4, 20, 3000, false),
+		{ID: 5, PlayerID: 10, Type: casino.WITHDRAWAL, Currency: "EUR", Amount: 1500, AmountEUR: 1500, CreatedAt: now},
+		{ID: 6, PlayerID: 20, Type: casino.BONUS_CREDIT, Currency: "EUR", Amount: 300, AmountEUR: 300, CreatedAt: now},
+	} {
+		pa.HandleEvent(event)
+	}
+
+	player, _ := pa.Player(10)
+	if player.WinCount != 1 || player.WinAmount != 2000 || player.WithdrawalAmount != 1500 {
+		t.Fatalf("player 10 = %+v, want one win paying 2000 and a withdrawal of 1500", player)
+	}
+	if bonus, _ := pa.Player(20); bonus.BonusCount != 1 || bonus.BonusAmount != 300 {
+		t.Fatalf("player 20 = %+v, want a bonus of 300", bonus)
+	}
+
+	// Stakes of 40 EUR minus a payout of 20 EUR, deposits of 50 EUR minus a withdrawal of 15 EUR
+	if ggr, netDeposits := pa.GGR(), pa.NetDeposits(); ggr != 20 || netDeposits != 35 {
+		t.Fatalf("GGR %v, net deposits %v, want 20 and 35", ggr, netDeposits)
+	}
+	if top := pa.Top(LEADERBOARD_NET_RESULT, WINDOW_ALL, 2); len(top) != 2 || top[0].PlayerID != 10 || top[0].Value != 1000 || top[1].Value != -3000 {
+		t.Fatalf("net result leaderboard = %+v, want player 10 winning 1000 and player 20 losing 3000", top)
+	}
+}
@@ -0,0 +1,154 @@
+package statistics
+
+import (
+	"sort"
+	"time"
+
+	"github.com/Bitstarz-eng/event-processing-challenge/internal/casino"
+)
+
+// Number of favourite games in the player profile
+const FAVOURITE_GAMES = 3
+
+// Fields of the player list sorting, PLAYER_SORT_ID is the default
+const (
+	PLAYER_SORT_ID             = "id"
+	PLAYER_SORT_BET_COUNT      = "bet_count"
+	PLAYER_SORT_BET_AMOUNT     = "bet_amount"
+	PLAYER_SORT_DEPOSIT_AMOUNT = "deposit_amount"
+	PLAYER_SORT_WIN_COUNT      = "win_count"
+	PLAYER_SORT_WIN_AMOUNT     = "win_amount"
+	PLAYER_SORT_NET_RESULT     = "net_result"
+	PLAYER_SORT_LAST_SEEN      = "last_seen"
+)
+
+var playerSortValues = map[string]func(PlayerSummary) int64{
+	PLAYER_SORT_ID:             func(ps PlayerSummary) int64 { return int64(ps.ID) },
+	PLAYER_SORT_BET_COUNT:      func(ps PlayerSummary) int64 { return ps.BetCount },
+	PLAYER_SORT_BET_AMOUNT:     func(ps PlayerSummary) int64 { return ps.BetAmount },
+	PLAYER_SORT_DEPOSIT_AMOUNT: func(ps PlayerSummary) int64 { return ps.DepositAmount },
+	PLAYER_SORT_WIN_COUNT:      func(ps PlayerSummary) int64 { return ps.WinCount },
+	PLAYER_SORT_WIN_AMOUNT:     func(ps PlayerSummary) int64 { return ps.WinAmount },
+	PLAYER_SORT_NET_RESULT:     func(ps PlayerSummary) int64 { return ps.NetResult },
+	PLAYER_SORT_LAST_SEEN:      func(ps PlayerSummary) int64 { return ps.LastSeen.UnixNano() },
+}
+
+// IsPlayerSort returns true if the players can be sorted by the field
+func IsPlayerSort(field string) bool {
+	_, ok := playerSortValues[field]
+	return ok
+}
+
+// PlayerSummary is a player of the /players list
+type PlayerSummary struct {
+	ID int `json:"id"`
+	PlayerSnapshot
+	NetResult int64 `json:"net_result"` // Payouts minus stakes in EUR cents
+}
+
+// PlayerActivity counts the events of the player per game and per currency
+type PlayerActivity struct {
+	Games      map[int]int64    `json:"games"`
+	Currencies map[string]int64 `json:"currencies"`
+}
+
+// GameActivity is a favourite game of the player
+type GameActivity struct {
+	ID     int    `json:"id"`
+	Name   string `json:"name"`
+	Events int64  `json:"events"`
+}
+
+// PlayerProfile is the lifetime and windowed statistics of one player
+type PlayerProfile struct {
+	ID             int                         `json:"id"`
+	Lifetime       PlayerSnapshot              `json:"lifetime"`
+	Windows        map[string]map[string]int64 `json:"windows"` // Window -> leaderboard metric -> value
+	FavouriteGames []GameActivity              `json:"favourite_games"`
+	Currencies     map[string]int64            `json:"currencies"` // Events per currency
+	LastSeen       time.Time                   `json:"last_seen"`
+}
+
+// Activity returns a copy of the games and the currencies of the player
+func (pd *PlayerData) Activity() PlayerActivity {
+	pd.mu.Lock()
+	defer pd.mu.Unlock()
+
+	activity := PlayerActivity{
+		Games:      make(map[int]int64, len(pd.games)),
+		Currencies: make(map[string]int64, len(pd.currencies)),
+	}
+	for gameId, events := range pd.games {
+		activity.Games[gameId] = events
+	}
+	for currency, events := range pd.currencies {
+		activity.Currencies[currency] = events
+	}
+	return activity
+}
+
+// Profile returns the statistics of the player, false if the player has no events
+func (pa *PlayerAggregator) Profile(id int) (*PlayerProfile, bool) {
+	pa.mu.RLock()
+	pd, ok := pa.players[id]
+	pa.mu.RUnlock()
+	if !ok {
+		return nil, false
+	}
+
+	lifetime := pd.Snapshot()
+	activity := pd.Activity()
+
+	favouriteGames := make([]GameActivity, 0, len(activity.Games))
+	for gameId, events := range activity.Games {
+		favouriteGames = append(favouriteGames, GameActivity{ID: gameId, Name: casino.Games[gameId].Title, Events: events})
+	}
+	sort.Slice(favouriteGames, func(i, j int) bool {
+		if favouriteGames[i].Events != favouriteGames[j].Events {
+			return favouriteGames[i].Events > favouriteGames[j].Events
+		}
+		return favouriteGames[i].ID < favouriteGames[j].ID
+	})
+	if len(favouriteGames) > FAVOURITE_GAMES {
+		favouriteGames = favouriteGames[:FAVOURITE_GAMES]
+	}
+
+	return &PlayerProfile{
+		ID:             id,
+		Lifetime:       lifetime,
+		Windows:        pa.Windows.Player(id),
+		FavouriteGames: favouriteGames,
+		Currencies:     activity.Currencies,
+		LastSeen:       lifetime.LastSeen,
+	}, true
+}
+
+// PlayersPage returns the limit players from the offset sorted by the field, and the number of all players.
+// Ties are ordered by the player ID, the field must be known.
+func (pa *PlayerAggregator) PlayersPage(field string, descending bool, offset, limit int) ([]PlayerSummary, int) {
+	pa.mu.RLock()
+	players := make([]PlayerSummary, 0, len(pa.players))
+	for id, pd := range pa.players {
+		players = append(players, PlayerSummary{ID: id, PlayerSnapshot: pd.Snapshot(), NetResult: pd.NetResult()})
+	}
+	pa.mu.RUnlock()
+
+	value := playerSortValues[field]
+	sort.Slice(players, func(i, j int) bool {
+		vi, vj := value(players[i]), value(players[j])
+		if vi != vj {
+			return (vi > vj) == descending
+		}
+		return players[i].ID < players[j].ID
+	})
+
+	total := len(players)
+	if offset > total {
+		offset = total
+	}
+	end := offset + limit
+	if end > total {
+		end = total
+	}
+	return players[offset:end], total
+}
@@ -0,0 +1,62 @@
+package statistics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Bitstarz-eng/event-processing-challenge/internal/casino"
+)
+
+func TestPlayerProfile(t *testing.T) {
+	// The windows end at the watermark, without the allowed lateness it is the latest event
+	t.Setenv("ALLOWED_LATENESS", "0s")
+	pa := NewPlayerAggregator()
+	now := time.Now()
+
+	old := bet(1, 1, 10, false)
+	old.CreatedAt = now.Add(-2 * time.Hour)
+	recent := bet(2, 1, 20, false)
+	recent.GameID, recent.CreatedAt = 101, now
+	stop := gameStop(1, 101)
+	stop.CreatedAt = now
+	for _, event := range []*casino.Event{old, recent, stop} {
+		pa.HandleEvent(event)
+	}
+
+	if _, ok := pa.Profile(2); ok {
+		t.Fatal("profile of a player without events")
+	}
+
+	profile, ok := pa.Profile(1)
+	if !ok {
+		t.Fatal("no profile of player 1")
+	}
+	if len(profile.FavouriteGames) != 2 || profile.FavouriteGames[0].ID != 101 || profile.FavouriteGames[0].Events != 2 {
+		t.Fatalf("favourite games = %+v, want 101 with 2 events first", profile.FavouriteGames)
+	}
+	if profile.Lifetime.BetAmount != 30 || profile.Windows[WINDOW_1H][LEADERBOARD_BET_AMOUNT] != 20 {
+		t.Fatalf("lifetime/1h bet amount = %d/%d, want 30/20", profile.Lifetime.BetAmount, profile.Windows[WINDOW_1H][LEADERBOARD_BET_AMOUNT])
+	}
+	if profile.Currencies["EUR"] != 2 || !profile.LastSeen.Equal(now) {
+		t.Fatalf("EUR events/last seen = %d/%v, want 2/%v", profile.Currencies["EUR"], profile.LastSeen, now)
+	}
+}
+
+func TestPlayersPage(t *testing.T) {
+	pa := NewPlayerAggregator()
+	for id := 1; id <= 5; id++ {
+		pa.HandleEvent(bet(id, id, id*10, false))
+	}
+
+	players, total := pa.PlayersPage(PLAYER_SORT_BET_AMOUNT, true, 1, 2)
+	if total != 5 || len(players) != 2 || players[0].ID != 4 || players[1].ID != 3 {
+		t.Fatalf("second page by bet amount = %+v of %d, want players 4 and 3 of 5", players, total)
+	}
+
+	if players, _ := pa.PlayersPage(PLAYER_SORT_ID, false, 4, 10); len(players) != 1 || players[0].ID != 5 {
+		t.Fatalf("last page by id = %+v, want player 5", players)
+	}
+	if players, _ := pa.PlayersPage(PLAYER_SORT_ID, false, 10, 10); len(players) != 0 {
+		t.Fatalf("page after the last player = %+v, want none", players)
+	}
+}
@@ -0,0 +1,62 @@
+package statistics
+
+import (
+	"math"
+	"time"
+)
+
+// The rates are calculated from the number of events per second of the event time
+const RATE_BUCKET = time.Second
+
+// The exponentially weighted moving average is calculated over this many windows,
+// older buckets weight less than 5% together
+const EWMA_HISTORY = 3
+
+type RateStats struct {
+	SMAPerSecond  float64 `json:"sma_per_second"`
+	EWMAPerSecond float64 `json:"ewma_per_second"`
+}
+
+// simpleMovingAverage returns the mean number of events per second, counts are ordered from the oldest second
+func simpleMovingAverage(counts []int64) float64 {
+	if len(counts) == 0 {
+		return 0
+	}
+
+	var sum int64
+	for _, count := range counts {
+		sum += count
+	}
+	return float64(sum) / float64(len(counts))
+}
+
+// exponentialMovingAverage returns the number of events per second where a second weights e^(-age/window),
+// counts are ordered from the oldest second
+func exponentialMovingAverage(counts []int64, window time.Duration) float64 {
+	if len(counts) == 0 {
+		return 0
+	}
+
+	alpha := 1 - math.Exp(-float64(RATE_BUCKET)/float64(window))
+	average := float64(counts[0])
+	for _, count := range counts[1:] {
+		average += alpha * (float64(count) - average)
+	}
+	return average
+}
+
+// Rounded to two decimals
+func roundRate(rate float64) float64 {
+	return math.Round(rate*100) / 100
+}
+
+// Returns the name of the window in the JSON, e.g. 1m, 15m, 1h
+func windowName(window time.Duration) string {
+	name := window.String()
+	for _, suffix := range []string{"0s", "0m"} {
+		if len(name) > len(suffix) && name[len(name)-len(suffix):] == suffix && name[len(name)-len(suffix)-1] >= 'a' {
+			name = name[:len(name)-len(suffix)]
+		}
+	}
+	return name
+}
@@ -0,0 +1,77 @@
+package statistics
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestMovingAverages(t *testing.T) {
+	if got := simpleMovingAverage([]int64{1, 2, 3, 6}); got != 3 {
+		t.Fatalf("SMA = %v, want 3", got)
+	}
+	if got := simpleMovingAverage(nil); got != 0 {
+		t.Fatalf("SMA of no seconds = %v, want 0", got)
+	}
+
+	constant := []int64{5, 5, 5, 5, 5}
+	if got := exponentialMovingAverage(constant, time.Minute); got != 5 {
+		t.Fatalf("EWMA of a constant rate = %v, want 5", got)
+	}
+
+	// A burst in the latest second weights more than the same burst a minute earlier, the oldest second seeds the average
+	recent, old := make([]int64, 62), make([]int64, 62)
+	recent[61], old[1] = 60, 60
+	alpha := 1 - math.Exp(-1.0/60)
+	if got := exponentialMovingAverage(recent, time.Minute); math.Abs(got-60*alpha) > 1e-9 {
+		t.Fatalf("EWMA of a recent burst = %v, want %v", got, 60*alpha)
+	}
+	if exponentialMovingAverage(old, time.Minute) >= exponentialMovingAverage(recent, time.Minute) {
+		t.Fatal("old burst weights more than the recent one")
+	}
+}
+
+func TestWindowName(t *testing.T) {
+	for window, want := range map[time.Duration]string{
+		time.Minute:      "1m",
+		15 * time.Minute: "15m",
+		time.Hour:        "1h",
+		90 * time.Second: "1m30s",
+		30 * time.Second: "30s",
+	} {
+		if got := windowName(window); got != want {
+			t.Errorf("name of %v = %q, want %q", window, got, want)
+		}
+	}
+}
+
+func TestTimeStatsRates(t *testing.T) {
+	t.Setenv("TIME_STATS_STORE", TIME_STATS_STORE_MEMORY)
+	t.Setenv("ALLOWED_LATENESS", "0s")
+	ctx := context.Background()
+	ts := NewTimeStats()
+
+	// Two events per second for five minutes, then the event which moves the watermark past them
+	start := time.Now().Truncate(RATE_BUCKET).Add(-time.Hour)
+	for second := 0; second < 300; second++ {
+		createdAt := start.Add(time.Duration(second) * time.Second)
+		ts.AddEvent(ctx, createdAt)
+		ts.AddEvent(ctx, createdAt.Add(500*time.Millisecond))
+	}
+	ts.AddEvent(ctx, start.Add(300*time.Second))
+
+	stats := ts.CalculateTimeStats()
+	if stats.TotalEvents != 601 || stats.EventsPerMinute != 120 || stats.MovingAvgPerSecond != 2 {
+		t.Fatalf("total %d, per minute %v, per second %v, want 601, 120 and 2", stats.TotalEvents, stats.EventsPerMinute, stats.MovingAvgPerSecond)
+	}
+	for window, want := range map[string]float64{"1m": 2, "5m": 2, "15m": 0.67} {
+		if got := stats.Rates[window].SMAPerSecond; got != want {
+			t.Errorf("%s SMA = %v, want %v", window, got, want)
+		}
+	}
+	// The EWMA of 15m still weights the empty seconds before the first event
+	if ewma := stats.Rates["15m"].EWMAPerSecond; ewma <= 0 || ewma >= 2 {
+		t.Errorf("15m EWMA = %v, want between 0 and 2", ewma)
+	}
+}
@@ -0,0 +1,183 @@
+package statistics
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Bitstarz-eng/event-processing-challenge/internal/casino"
+)
+
+// Default inactivity of a player on a game before the session is closed, configured by SESSION_TIMEOUT
+const DEFAULT_SESSION_TIMEOUT = 5 * time.Minute
+
+// Reasons of the closed sessions
+const (
+	SESSION_CLOSED_STOP    = "game_stop" // The game_stop event of the session
+	SESSION_CLOSED_TIMEOUT = "timeout"   // No event of the player on the game for the timeout
+	SESSION_CLOSED_RESTART = "restart"   // A game_start event before the game_stop event
+)
+
+type sessionKey struct {
+	playerID int
+	gameID   int
+}
+
+type openSession struct {
+	start        time.Time
+	lastActivity time.Time
+	bets         int64
+}
+
+// SessionStats is the read-only view of the sessions served by the /sessions API
+type SessionStats struct {
+	ActiveSessions     int              `json:"active_sessions"`
+	PeakActiveSessions int              `json:"peak_active_sessions"`
+	ActivePerGame      map[int]int      `json:"active_per_game"`
+	OpenedSessions     int64            `json:"opened_sessions"`
+	ClosedSessions     map[string]int64 `json:"closed_sessions"` // Per reason
+	UnmatchedStops     int64            `json:"unmatched_stops"` // game_stop events without an open session
+	DurationSeconds    QuantileSummary  `json:"duration_seconds"`
+	BetsPerSession     QuantileSummary  `json:"bets_per_session"`
+}
+
+// SessionTracker pairs the game_start and game_stop events of each player and game.
+// A session is opened by game_start or by the first bet or win without it, and closed by game_stop or after the timeout.
+// The sessions follow the event time of the watermark, which advances by the wall-clock time while no events arrive,
+// so the inactive sessions are closed when the traffic stops. It is safe for concurrent events and reads.
+type SessionTracker struct {
+	mu        sync.Mutex
+	timeout   time.Duration
+	watermark *Watermark
+	sessions  map[sessionKey]*openSession
+
+	peakActive     int
+	opened         int64
+	closed         map[string]int64
+	unmatchedStops int64
+	durations      *DDSketch // Seconds
+	bets           *DDSketch
+}
+
+func NewSessionTracker(timeout time.Duration) *SessionTracker {
+	return &SessionTracker{
+		timeout:   timeout,
+		watermark: NewWatermark(),
+		sessions:  make(map[sessionKey]*openSession),
+		closed:    make(map[string]int64),
+		durations: NewDDSketch(),
+		bets:      NewDDSketch(),
+	}
+}
+
+func (st *SessionTracker) HandleEvent(event *casino.Event) {
+	if event.GameID == 0 {
+		// Deposits, withdrawals and bonus credits are not related to a game
+		return
+	}
+
+	// Late events are still paired, the watermark only closes the inactive sessions
+	eventTime, _ := st.watermark.Observe(event.CreatedAt)
+	key := sessionKey{playerID: event.PlayerID, gameID: event.GameID}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	session, ok := st.sessions[key]
+	if ok && eventTime.Sub(session.lastActivity) > st.timeout {
+		st.close(key, session, SESSION_CLOSED_TIMEOUT, session.lastActivity)
+		session, ok = nil, false
+	}
+
+	switch event.Type {
+	case casino.GAME_START:
+		if ok {
+			st.close(key, session, SESSION_CLOSED_RESTART, session.lastActivity)
+		}
+		st.open(key, eventTime)
+	case casino.GAME_STOP:
+		if !ok {
+			st.unmatchedStops++
+			return
+		}
+		st.close(key, session, SESSION_CLOSED_STOP, eventTime)
+	default:
+		if !ok {
+			session = st.open(key, eventTime)
+		}
+		if eventTime.After(session.lastActivity) {
+			session.lastActivity = eventTime
+		}
+		if event.Type == casino.BET {
+			session.bets++
+		}
+	}
+}
+
+// Expire closes the sessions without an event for the timeout before the idle watermark
+func (st *SessionTracker) Expire() {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	st.expire()
+}
+
+// Stats returns a copy of the session statistics, the inactive sessions are closed first
+func (st *SessionTracker) Stats() *SessionStats {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	st.expire()
+
+	stats := &SessionStats{
+		ActiveSessions:     len(st.sessions),
+		PeakActiveSessions: st.peakActive,
+		ActivePerGame:      make(map[int]int),
+		OpenedSessions:     st.opened,
+		ClosedSessions:     make(map[string]int64, len(st.closed)),
+		UnmatchedStops:     st.unmatchedStops,
+		DurationSeconds:    st.durations.Summary(),
+		BetsPerSession:     st.bets.Summary(),
+	}
+	for key := range st.sessions {
+		stats.ActivePerGame[key.gameID]++
+	}
+	for reason, count := range st.closed {
+		stats.ClosedSessions[reason] = count
+	}
+	return stats
+}
+
+func (st *SessionTracker) open(key sessionKey, start time.Time) *openSession {
+	session := &openSession{
+		start:        start,
+		lastActivity: start,
+	}
+	st.sessions[key] = session
+	st.opened++
+	if len(st.sessions) > st.peakActive {
+		st.peakActive = len(st.sessions)
+	}
+	return session
+}
+
+func (st *SessionTracker) close(key sessionKey, session *openSession, reason string, end time.Time) {
+	delete(st.sessions, key)
+	st.closed[reason]++
+
+	// Events may arrive out of order, a session is never shorter than zero
+	duration := end.Sub(session.start)
+	if duration < 0 {
+		duration = 0
+	}
+	st.durations.Add(duration.Seconds())
+	st.bets.Add(float64(session.bets))
+}
+
+func (st *SessionTracker) expire() {
+	inactiveBefore := st.watermark.IdleNow().Add(-st.timeout)
+	for key, session := range st.sessions {
+		if session.lastActivity.Before(inactiveBefore) {
+			st.close(key, session, SESSION_CLOSED_TIMEOUT, session.lastActivity)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package statistics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Bitstarz-eng/event-processing-challenge/internal/casino"
+)
+
+func TestSessionTracker(t *testing.T) {
+	st := NewSessionTracker(time.Minute)
+	start := time.Now().Add(-time.Hour)
+
+	for _, event := range []casino.Event{
+		// Player 1 plays a session of 30s with two bets
+		{PlayerID: 1, GameID: 100, Type: casino.GAME_START, CreatedAt: start},
+		{PlayerID: 1, GameID: 100, Type: casino.BET, CreatedAt: start.Add(10 * time.Second)},
+		{PlayerID: 1, GameID: 100, Type: casino.BET, CreatedAt: start.Add(20 * time.Second)},
+		{PlayerID: 1, GameID: 100, Type: casino.GAME_STOP, CreatedAt: start.Add(30 * time.Second)},
+
+		// Player 2 never stops the game, the session times out after the bet
+		{PlayerID: 2, GameID: 101, Type: casino.GAME_START, CreatedAt: start},
+		{PlayerID: 2, GameID: 101, Type: casino.BET, CreatedAt: start.Add(5 * time.Second)},
+
+		// Player 3 stops a game without a session, then starts a session which is still active
+		{PlayerID: 3, GameID: 100, Type: casino.GAME_STOP, CreatedAt: start.Add(5 * time.Minute)},
+		{PlayerID: 3, GameID: 100, Type: casino.GAME_START, CreatedAt: start.Add(5 * time.Minute)},
+	} {
+		event := event
+		st.HandleEvent(&event)
+	}
+
+	stats := st.Stats()
+	if stats.OpenedSessions != 3 || stats.ActiveSessions != 1 || stats.ActivePerGame[100] != 1 || stats.PeakActiveSessions != 2 {
+		t.Fatalf("opened/active/active on 100/peak = %d/%d/%d/%d, want 3/1/1/2",
+			stats.OpenedSessions, stats.ActiveSessions, stats.ActivePerGame[100], stats.PeakActiveSessions)
+	}
+	if stats.ClosedSessions[SESSION_CLOSED_STOP] != 1 || stats.ClosedSessions[SESSION_CLOSED_TIMEOUT] != 1 || stats.UnmatchedStops != 1 {
+		t.Fatalf("closed sessions = %v, unmatched stops = %d, want 1 stopped, 1 timed out and 1 unmatched", stats.ClosedSessions, stats.UnmatchedStops)
+	}
+	if stats.BetsPerSession.Count != 2 || stats.BetsPerSession.Max != 2 || stats.DurationSeconds.Max != 30 {
+		t.Fatalf("bets per session = %+v, duration = %+v, want 2 sessions of up to 2 bets and 30s", stats.BetsPerSession, stats.DurationSeconds)
+	}
+}
+
+func TestSessionTrackerTimesOutWithoutEvents(t *testing.T) {
+	t.Setenv("ALLOWED_LATENESS", "10ms")
+	st := NewSessionTracker(50 * time.Millisecond)
+
+	start := &casino.Event{PlayerID: 1, GameID: 100, Type: casino.GAME_START, CreatedAt: time.Now()}
+	st.HandleEvent(start)
+	if stats := st.Stats(); stats.ActiveSessions != 1 {
+		t.Fatalf("active sessions = %d, want 1", stats.ActiveSessions)
+	}
+
+	// No more events arrive
+	time.Sleep(100 * time.Millisecond)
+	st.Expire()
+
+	if stats := st.Stats(); stats.ActiveSessions != 0 || stats.ClosedSessions[SESSION_CLOSED_TIMEOUT] != 1 {
+		t.Fatalf("active sessions = %d, closed = %v, want the session timed out", stats.ActiveSessions, stats.ClosedSessions)
+	}
+}
@@ -0,0 +1,377 @@
+package statistics
+
+import (
+	"context"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Versions of the snapshot states, increase the version when the state changes incompatibly
+const (
+	PLAYER_STATE_VERSION = 4
+	GAME_STATE_VERSION   = 2
+	TIME_STATE_VERSION   = 2
+)
+
+type WatermarkState struct {
+	MaxEventTime int64 `json:"max_event_time"` // Unix nanoseconds
+}
+
+func (w *Watermark) State() WatermarkState {
+	return WatermarkState{
+		MaxEventTime: w.maxEventTime.Load(),
+	}
+}
+
+// Restore advances the watermark to the state, it never moves back
+func (w *Watermark) Restore(state WatermarkState) {
+	w.advance(state.MaxEventTime)
+}
+
+type WindowBucketState struct {
+	Minute int64            `json:"minute"`
+	Values map[string]int64 `json:"values"`
+}
+
+// PlayerState is the snapshot of the PlayerAggregator, the leaderboards and totals are rebuilt from the players
+type PlayerState struct {
+	Players       map[int]PlayerSnapshot        `json:"players"`
+	Activity      map[int]PlayerActivity        `json:"activity"`
+	Windows       map[int][]WindowBucketState   `json:"windows"`
+	Watermark     WatermarkState                `json:"watermark"`
+	LateEvents    int64                         `json:"late_events"`
+	Distributions map[string]DistributionsState `json:"distributions"`
+}
+
+// State copies the statistics, the players, windows and distributions are read under separate locks,
+// so the caller pauses the events while it is taken (the subscriber pauses its workers)
+func (pa *PlayerAggregator) State() *PlayerState {
+	state := &PlayerState{
+		Players:       pa.Players(),
+		Activity:      make(map[int]PlayerActivity),
+		Windows:       make(map[int][]WindowBucketState),
+		Watermark:     pa.Windows.watermark.State(),
+		LateEvents:    pa.Windows.LateEvents(),
+		Distributions: pa.Distributions.State(),
+	}
+
+	pa.mu.RLock()
+	for id, pd := range pa.players {
+		state.Activity[id] = pd.Activity()
+	}
+	pa.mu.RUnlock()
+
+	pa.Windows.mu.Lock()
+	defer pa.Windows.mu.Unlock()
+	for playerID, buckets := range pa.Windows.buckets {
+		for _, bucket := range buckets {
+			values := make(map[string]int64, len(bucket.values))
+			for metric, value := range bucket.values {
+				values[metric] = value
+			}
+			state.Windows[playerID] = append(state.Windows[playerID], WindowBucketState{Minute: bucket.minute, Values: values})
+		}
+	}
+	return state
+}
+
+// Restore loads the state into the aggregator before the first event
+func (pa *PlayerAggregator) Restore(state *PlayerState) {
+	for id, player := range state.Players {
+		pd := pa.player(id)
+		pd.BetCount.Store(player.BetCount)
+		pd.BetAmount.Store(player.BetAmount)
+		pd.DepositCount.Store(player.DepositCount)
+		pd.DepositAmount.Store(player.DepositAmount)
+		pd.WonCount.Store(player.WinCount)
+		pd.WinAmount.Store(player.WinAmount)
+		pd.WithdrawalCount.Store(player.WithdrawalCount)
+		pd.WithdrawalAmount.Store(player.WithdrawalAmount)
+		pd.BonusCount.Store(player.BonusCount)
+		pd.BonusAmount.Store(player.BonusAmount)
+		if !player.LastSeen.IsZero() {
+			pd.LastSeen.Store(player.LastSeen.UnixNano())
+		}
+		for gameId, events := range state.Activity[id].Games {
+			pd.games[gameId] = events
+		}
+		for currency, events := range state.Activity[id].Currencies {
+			pd.currencies[currency] = events
+		}
+
+		pa.TotalBetAmount.Add(player.BetAmount)
+		pa.TotalWinAmount.Add(player.WinAmount)
+		pa.TotalDepositAmount.Add(player.DepositAmount)
+		pa.TotalWithdrawalAmount.Add(player.WithdrawalAmount)
+		pa.TotalBonusAmount.Add(player.BonusAmount)
+
+		// A player is ranked only on the metrics of its events, like in HandleEvent
+		if player.BetCount > 0 {
+			pa.Leaderboards[LEADERBOARD_BET_COUNT].Update(id, player.BetCount)
+			pa.Leaderboards[LEADERBOARD_BET_AMOUNT].Update(id, player.BetAmount)
+		}
+		if player.DepositCount > 0 {
+			pa.Leaderboards[LEADERBOARD_DEPOSIT_SUM].Update(id, player.DepositAmount)
+		}
+		if player.WinCount > 0 {
+			pa.Leaderboards[LEADERBOARD_WIN_COUNT].Update(id, player.WinCount)
+		}
+		if player.BetCount > 0 || player.WinAmount > 0 {
+			pa.Leaderboards[LEADERBOARD_NET_RESULT].Update(id, pd.NetResult())
+		}
+	}
+
+	pa.Windows.watermark.Restore(state.Watermark)
+	pa.Windows.lateEvents.Store(state.LateEvents)
+	pa.Distributions.Restore(state.Distributions)
+
+	pa.Windows.mu.Lock()
+	defer pa.Windows.mu.Unlock()
+	for playerID, buckets := range state.Windows {
+		for _, bucket := range buckets {
+			restored := pa.Windows.bucket(playerID, bucket.Minute)
+			for metric, value := range bucket.Values {
+				restored.values[metric] += value
+			}
+		}
+	}
+}
+
+type DistributionState struct {
+	Bins      map[int]int64 `json:"bins"`
+	ZeroCount int64         `json:"zero_count"`
+	Count     int64         `json:"count"`
+	Sum       float64       `json:"sum"`
+	Min       float64       `json:"min"`
+	Max       float64       `json:"max"`
+	Histogram []int64       `json:"histogram"`
+}
+
+func (d *Distribution) State() DistributionState {
+	state := DistributionState{
+		Bins:      make(map[int]int64, len(d.sketch.bins)),
+		ZeroCount: d.sketch.zeroCount,
+		Count:     d.sketch.count,
+		Sum:       d.sketch.sum,
+		Min:       d.sketch.min,
+		Max:       d.sketch.max,
+		Histogram: make([]int64, len(d.histogram)),
+	}
+	for index, count := range d.sketch.bins {
+		state.Bins[index] = count
+	}
+	copy(state.Histogram, d.histogram)
+	return state
+}
+
+func distributionFromState(state DistributionState) *Distribution {
+	d := NewDistribution()
+	for index, count := range state.Bins {
+		d.sketch.bins[index] = count
+	}
+	d.sketch.zeroCount = state.ZeroCount
+	d.sketch.count = state.Count
+	d.sketch.sum = state.Sum
+	d.sketch.min = state.Min
+	d.sketch.max = state.Max
+	copy(d.histogram, state.Histogram)
+	return d
+}
+
+// DistributionsState is the snapshot of the distributions of one metric
+type DistributionsState struct {
+	All        DistributionState            `json:"all"`
+	Games      map[int]DistributionState    `json:"games"`
+	Currencies map[string]DistributionState `json:"currencies"`
+}
+
+func (ad *AmountDistributions) State() map[string]DistributionsState {
+	ad.mu.Lock()
+	defer ad.mu.Unlock()
+
+	state := make(map[string]DistributionsState, len(ad.metrics))
+	for metric, md := range ad.metrics {
+		metricState := DistributionsState{
+			All:        md.all.State(),
+			Games:      make(map[int]DistributionState, len(md.games)),
+			Currencies: make(map[string]DistributionState, len(md.currencies)),
+		}
+		for gameId, distribution := range md.games {
+			metricState.Games[gameId] = distribution.State()
+		}
+		for currency, distribution := range md.currencies {
+			metricState.Currencies[currency] = distribution.State()
+		}
+		state[metric] = metricState
+	}
+	return state
+}
+
+// Restore loads the state before the first event, unknown metrics are skipped
+func (ad *AmountDistributions) Restore(state map[string]DistributionsState) {
+	ad.mu.Lock()
+	defer ad.mu.Unlock()
+
+	for metric, metricState := range state {
+		md, ok := ad.metrics[metric]
+		if !ok {
+			continue
+		}
+		md.all = distributionFromState(metricState.All)
+		for gameId, distribution := range metricState.Games {
+			md.games[gameId] = distributionFromState(distribution)
+		}
+		for currency, distribution := range metricState.Currencies {
+			md.currencies[currency] = distributionFromState(distribution)
+		}
+	}
+}
+
+type GameDataState struct {
+	Id                int                `json:"id"`
+	GamePlayedCounter int                `json:"game_played_count"`
+	BetPerCurrency    map[string]float64 `json:"bet_per_currency"`
+	BetCount          int                `json:"bet_count"`
+	StakeAmount       int64              `json:"stake_amount"`
+	PayoutAmount      int64              `json:"payout_amount"`
+}
+
+// GameState is the snapshot of the GameAggregator
+type GameState struct {
+	Games          []GameDataState `json:"games"`
+	MostPlayedGame StatisticAmount `json:"most_played_game"` // Amount is the game_stop count
+	MostBettedGame StatisticAmount `json:"most_betted_game"`
+	Unique         UniqueState     `json:"unique_players"`
+}
+
+func (ga *GameAggregator) State() *GameState {
+	ga.topMu.Lock()
+	state := &GameState{
+		MostPlayedGame: StatisticAmount{Id: ga.mostPlayedGame.Id, Amount: ga.mostPlayedGame.Count},
+		MostBettedGame: ga.mostBettedGame,
+	}
+	ga.topMu.Unlock()
+
+	state.Unique = ga.Unique.State()
+	for _, gd := range ga.gameData() {
+		gd.mu.Lock()
+		game := GameDataState{
+			Id:                gd.Id,
+			GamePlayedCounter: gd.GamePlayedCounter,
+			BetPerCurrency:    make(map[string]float64, len(gd.BetPerCurrency)),
+			BetCount:          gd.BetCount,
+			StakeAmount:       gd.StakeAmount,
+			PayoutAmount:      gd.PayoutAmount,
+		}
+		for currency, amount := range gd.BetPerCurrency {
+			game.BetPerCurrency[currency] = amount
+		}
+		gd.mu.Unlock()
+		state.Games = append(state.Games, game)
+	}
+	sort.Slice(state.Games, func(i, j int) bool {
+		return state.Games[i].Id < state.Games[j].Id
+	})
+	return state
+}
+
+// Restore loads the state into the aggregator before the first event
+func (ga *GameAggregator) Restore(state *GameState) {
+	ga.mu.Lock()
+	defer ga.mu.Unlock()
+
+	for _, game := range state.Games {
+		gd := NewGameData(game.Id)
+		gd.GamePlayedCounter = game.GamePlayedCounter
+		gd.BetCount = game.BetCount
+		gd.StakeAmount = game.StakeAmount
+		gd.PayoutAmount = game.PayoutAmount
+		for currency, amount := range game.BetPerCurrency {
+			gd.BetPerCurrency[currency] = amount
+		}
+		ga.games[game.Id] = gd
+	}
+
+	ga.topMu.Lock()
+	defer ga.topMu.Unlock()
+	ga.mostPlayedGame.SetValues(state.MostPlayedGame.Id, state.MostPlayedGame.Amount)
+	ga.mostBettedGame = state.MostBettedGame
+	ga.Unique.Restore(state.Unique)
+}
+
+// UniqueState is the snapshot of the UniquePlayers, the sketches are encoded by the backend
+type UniqueState struct {
+	Backend   string            `json:"backend"`
+	Sketches  map[string][]byte `json:"sketches"`
+	Watermark WatermarkState    `json:"watermark"`
+}
+
+func (up *UniquePlayers) State() UniqueState {
+	return UniqueState{
+		Backend:   up.backend,
+		Sketches:  up.Store.Sketches(context.Background()),
+		Watermark: up.watermark.State(),
+	}
+}
+
+// Restore loads the sketches before the first event, the sketches of another backend are skipped
+func (up *UniquePlayers) Restore(state UniqueState) {
+	if state.Backend != up.backend {
+		log.Printf("Skipped unique players of the %s backend, HLL_BACKEND is %s", state.Backend, up.backend)
+		return
+	}
+	if err := up.Store.Restore(context.Background(), state.Sketches); err != nil {
+		log.Printf("Failed to restore unique players: %v", err)
+		return
+	}
+	up.watermark.Restore(state.Watermark)
+
+	up.mu.Lock()
+	defer up.mu.Unlock()
+	for key := range state.Sketches {
+		switch {
+		case strings.HasPrefix(key, UNIQUE_MINUTE+":"):
+			minute, err := strconv.ParseInt(strings.TrimPrefix(key, UNIQUE_MINUTE+":"), 10, 64)
+			if err != nil {
+				log.Printf("Invalid unique players key %s: %v", key, err)
+				continue
+			}
+			up.minutes[minute] = struct{}{}
+		case strings.HasPrefix(key, UNIQUE_CURRENCY+":"):
+			up.currencies[strings.TrimPrefix(key, UNIQUE_CURRENCY+":")] = struct{}{}
+		}
+	}
+}
+
+// TimeState is the snapshot of the TimeStats, the event counts of each second in the retention until End
+type TimeState struct {
+	TotalEvents int            `json:"total_events"`
+	End         int64          `json:"end"` // Unix seconds, exclusive
+	Counts      []int64        `json:"counts"`
+	Watermark   WatermarkState `json:"watermark"`
+	LateEvents  int64          `json:"late_events"`
+}
+
+func (ts *TimeStats) State(ctx context.Context) *TimeState {
+	// Seconds after the watermark are still counted, so the snapshot ends after the latest event
+	end := ts.Watermark.Latest().Truncate(RATE_BUCKET).Add(RATE_BUCKET)
+
+	return &TimeState{
+		TotalEvents: ts.Store.TotalEvents(ctx),
+		End:         end.Unix(),
+		Counts:      ts.Store.Counts(ctx, end, ts.retention()),
+		Watermark:   ts.Watermark.State(),
+		LateEvents:  ts.lateEvents.Load(),
+	}
+}
+
+// Restore loads the state into the store before the first event, the watermark first since the store expires the counts by it
+func (ts *TimeStats) Restore(ctx context.Context, state *TimeState) {
+	ts.Watermark.Restore(state.Watermark)
+	ts.Store.Restore(ctx, state.TotalEvents, time.Unix(state.End, 0), state.Counts)
+	ts.lateEvents.Store(state.LateEvents)
+	ts.resetCounts()
+}
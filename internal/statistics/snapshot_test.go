@@ -0,0 +1,123 @@
+package statistics
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Bitstarz-eng/event-processing-challenge/internal/casino"
+)
+
+// The snapshots are saved and loaded as JSON
+func roundTrip(t *testing.T, state, restored interface{}) {
+	data, err := json.Marshal(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, restored); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPlayerStateRoundTrip(t *testing.T) {
+	pa := NewPlayerAggregator()
+	pa.HandleEvent(bet(1, 10, 500, true))
+	pa.HandleEvent(bet(2, 30, 200, false))
+	// Player 20 only deposits
+	pa.HandleEvent(&casino.Event{ID: 3, PlayerID: 20, Type: casino.DEPOSIT, Currency: "EUR", Amount: 1000, AmountEUR: 1000, CreatedAt: time.Now()})
+
+	var state PlayerState
+	roundTrip(t, pa.State(), &state)
+	restored := NewPlayerAggregator()
+	restored.Restore(&state)
+
+	if !reflect.DeepEqual(restored.Players(), pa.Players()) {
+		t.Fatalf("restored players = %+v, want %+v", restored.Players(), pa.Players())
+	}
+	for _, metric := range LeaderboardMetrics {
+		for _, window := range []string{WINDOW_ALL, WINDOW_1H} {
+			if got, want := restored.Top(metric, window, 10), pa.Top(metric, window, 10); !reflect.DeepEqual(got, want) {
+				t.Fatalf("restored %s leaderboard in %s = %+v, want %+v", metric, window, got, want)
+			}
+		}
+	}
+	if restored.GGR() != pa.GGR() || restored.NetDeposits() != pa.NetDeposits() {
+		t.Fatalf("restored GGR/net deposits = %v/%v, want %v/%v", restored.GGR(), restored.NetDeposits(), pa.GGR(), pa.NetDeposits())
+	}
+	got, _ := restored.Distributions.Stats(casino.BET)
+	want, _ := pa.Distributions.Stats(casino.BET)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("restored bet distribution = %+v, want %+v", got, want)
+	}
+}
+
+func TestPlayerRestoreRanksOnlyPlayedMetrics(t *testing.T) {
+	pa := NewPlayerAggregator()
+	pa.HandleEvent(&casino.Event{ID: 1, PlayerID: 20, Type: casino.DEPOSIT, Currency: "EUR", Amount: 1000, AmountEUR: 1000, CreatedAt: time.Now()})
+
+	restored := NewPlayerAggregator()
+	restored.Restore(pa.State())
+
+	for _, metric := range []string{LEADERBOARD_BET_COUNT, LEADERBOARD_BET_AMOUNT, LEADERBOARD_WIN_COUNT, LEADERBOARD_NET_RESULT} {
+		if top := restored.Top(metric, WINDOW_ALL, 10); len(top) != 0 {
+			t.Fatalf("deposit-only player ranked on %s: %+v", metric, top)
+		}
+	}
+	if top := restored.Top(LEADERBOARD_DEPOSIT_SUM, WINDOW_ALL, 10); len(top) != 1 || top[0].PlayerID != 20 {
+		t.Fatalf("deposit leaderboard = %+v, want player 20", top)
+	}
+}
+
+func TestGameStateRoundTrip(t *testing.T) {
+	ga := NewGameAggregator()
+	ga.HandleEvent(bet(1, 10, 500, false))
+	ga.HandleEvent(bet(2, 11, 700, false))
+	ga.HandleEvent(gameStop(10, 100))
+	ga.HandleEvent(gameStop(11, 101))
+
+	var state GameState
+	roundTrip(t, ga.State(), &state)
+	restored := NewGameAggregator()
+	restored.Restore(&state)
+
+	if got, want := restored.Snapshot(), ga.Snapshot(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("restored game stats = %+v, want %+v", got, want)
+	}
+}
+
+func TestTimeStateRoundTrip(t *testing.T) {
+	t.Setenv("TIME_STATS_STORE", TIME_STATS_STORE_MEMORY)
+	ctx := context.Background()
+
+	ts := NewTimeStats()
+	now := time.Now()
+	for i := 0; i < 30; i++ {
+		ts.AddEvent(ctx, now.Add(-time.Duration(i)*time.Second))
+	}
+
+	var state TimeState
+	roundTrip(t, ts.State(ctx), &state)
+	restored := NewTimeStats()
+	restored.Restore(ctx, &state)
+
+	got, want := restored.CalculateTimeStats(), ts.CalculateTimeStats()
+	if got.TotalEvents != want.TotalEvents || got.EventsPerMinute != want.EventsPerMinute || !reflect.DeepEqual(got.Rates, want.Rates) {
+		t.Fatalf("restored time stats = %+v, want %+v", got, want)
+	}
+}
+
+func TestTimeStateEndsNowInProcessingTime(t *testing.T) {
+	t.Setenv("TIME_STATS_STORE", TIME_STATS_STORE_MEMORY)
+	t.Setenv("TIME_SEMANTICS", TIME_SEMANTICS_PROCESSING)
+	t.Setenv("ALLOWED_LATENESS", "1h")
+	ctx := context.Background()
+
+	ts := NewTimeStats()
+	ts.AddEvent(ctx, time.Now())
+
+	if end := time.Unix(ts.State(ctx).End, 0); end.After(time.Now().Add(RATE_BUCKET)) {
+		t.Fatalf("state ends at %v, want the current second", end)
+	}
+}
@@ -23,3 +23,9 @@ type StatisticAmount struct {
 	Id     int `json:"id"`
 	Amount int `json:"amount"`
 }
+
+// StatisticAmountEUR is the served StatisticAmount of an amount in EUR cents
+type StatisticAmountEUR struct {
+	Id        int     `json:"id"`
+	AmountEUR float64 `json:"amount_eur"`
+}
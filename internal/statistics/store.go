@@ -0,0 +1,41 @@
+package statistics
+
+import (
+	"encoding/json"
+	"log"
+	"sync/atomic"
+)
+
+type StoreStats struct {
+	Stored     atomic.Int64 `json:"stored"`
+	Duplicates atomic.Int64 `json:"duplicates"`
+	Failed     atomic.Int64 `json:"failed_flushes"`
+	Dropped    atomic.Int64 `json:"dropped"`
+}
+
+func NewStoreStats() *StoreStats {
+	return &StoreStats{}
+}
+
+// Count the result of one batch insert
+func (ss *StoreStats) AddBatch(size int, inserted int64) {
+	ss.Stored.Add(inserted)
+	ss.Duplicates.Add(int64(size) - inserted)
+}
+
+func (ss *StoreStats) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]int64{
+		"stored":         ss.Stored.Load(),
+		"duplicates":     ss.Duplicates.Load(),
+		"failed_flushes": ss.Failed.Load(),
+		"dropped":        ss.Dropped.Load(),
+	})
+}
+
+func (ss *StoreStats) String() string {
+	storeStats, err := json.MarshalIndent(ss, "", "  ")
+	if err != nil {
+		log.Println("Error marshaling StoreStats to JSON:", err)
+	}
+	return string(storeStats)
+}
@@ -3,101 +3,178 @@ package statistics
 import (
 	"context"
 	"encoding/json"
-	"fmt"
 	"log"
-	"math"
+	"sync"
+	"sync/atomic"
 	"time"
 
-	rds "github.com/Bitstarz-eng/event-processing-challenge/internal/redis"
-	"github.com/go-redis/redis/v8"
+	"github.com/Bitstarz-eng/event-processing-challenge/internal/config"
 )
 
+// Implementations of the TimeStatsStore, configured by TIME_STATS_STORE
 const (
-	TOTAL_EVENTS          = "total_events"
-	EVENTS_PER_MINUTE     = "event_per_minute"
-	MOVING_AVG_PER_SECOND = "moving_avg_per_second"
+	TIME_STATS_STORE_REDIS  = "redis"
+	TIME_STATS_STORE_MEMORY = "memory"
 )
 
+// The README metrics: events in the last minute and events per second as a moving average in the last minute
+const MATERIALIZED_WINDOW = time.Minute
+
+// The counts of the last minute before the watermark are read from the store on every calculation, older counts are cached.
+// An event accepted just before the watermark passed its second may still be being added to the store.
+const RATE_REFRESH_WINDOW = time.Minute
+
+// Default windows of the moving averages, configured by TIME_STATS_WINDOWS
+var DEFAULT_RATE_WINDOWS = []time.Duration{time.Minute, 5 * time.Minute, 15 * time.Minute}
+
+// TimeStatsStore counts the events in total and per second of their creation time
+type TimeStatsStore interface {
+	AddEvent(ctx context.Context, createdAt time.Time)
+	TotalEvents(ctx context.Context) int
+
+	// Counts returns the event counts of the seconds in [end-duration, end), ordered from the oldest second
+	Counts(ctx context.Context, end time.Time, duration time.Duration) []int64
+
+	Reset(ctx context.Context)
+
+	// Restore sets the total and the counts of the seconds in [end-len(counts), end), ordered from the oldest second
+	Restore(ctx context.Context, totalEvents int, end time.Time, counts []int64)
+}
+
 type TimeStats struct {
-	RedisClient        *redis.Client `json:"-"`
-	TotalEvents        int           `json:"total_events"`
-	EventsPerMinute    int64         `json:"events_per_minute"`
-	MovingAvgPerSecond float64       `json:"moving_avg_per_second"`
+	Store              TimeStatsStore       `json:"-"`
+	Windows            []time.Duration      `json:"-"`
+	Watermark          *Watermark           `json:"-"`
+	TotalEvents        int                  `json:"total_events"`
+	LateEvents         int64                `json:"late_events"`
+	WatermarkTime      time.Time            `json:"watermark"`
+	EventsPerMinute    float64              `json:"events_per_minute"`
+	MovingAvgPerSecond float64              `json:"moving_avg_per_second"`
+	Rates              map[string]RateStats `json:"rates"`
+
+	lateEvents atomic.Int64
+
+	// Counts of the seconds in the retention before countsEnd, nil until the first calculation
+	countsMu  sync.Mutex
+	counts    []int64
+	countsEnd time.Time
 }
 
 func NewTimeStats() *TimeStats {
-	return &TimeStats{
-		RedisClient: rds.GetRedisClient(),
+	windows := config.GetDurations("TIME_STATS_WINDOWS", DEFAULT_RATE_WINDOWS)
+	for _, window := range windows {
+		if window < RATE_BUCKET || window%RATE_BUCKET != 0 {
+			log.Fatalf("TIME_STATS_WINDOWS must be whole seconds, got %v", window)
+		}
+	}
+
+	ts := &TimeStats{
+		Windows:   windows,
+		Watermark: NewWatermark(),
+	}
+
+	switch store := config.GetString("TIME_STATS_STORE", TIME_STATS_STORE_REDIS); store {
+	case TIME_STATS_STORE_REDIS:
+		ts.Store = NewRedisTimeStatsStore(ts.retention(), ts.Watermark)
+	case TIME_STATS_STORE_MEMORY:
+		ts.Store = NewMemoryTimeStatsStore(ts.retention())
+	default:
+		log.Fatalf("Unknown TIME_STATS_STORE %q", store)
+	}
+	return ts
+}
+
+// Buckets are kept for the longest EWMA history
+func (ts *TimeStats) retention() time.Duration {
+	retention := MATERIALIZED_WINDOW
+	for _, window := range ts.Windows {
+		if window > retention {
+			retention = window
+		}
 	}
+	return EWMA_HISTORY * retention
 }
 
-func (ts *TimeStats) CalculateTimeStats() {
+// CalculateTimeStats returns a snapshot of the time statistics
+func (ts *TimeStats) CalculateTimeStats() *TimeStats {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
-	currentTimestamp := float64(time.Now().Unix())
 
-	// Total events
-	totalEvents, err := ts.RedisClient.Get(ctx, TOTAL_EVENTS).Int()
-	if err != nil && err != redis.Nil {
-		log.Fatalf("Error getting total events: %v", err)
+	// Late events are not in the store, but they are in the total
+	lateEvents := ts.lateEvents.Load()
+	totalEvents := ts.Store.TotalEvents(ctx) + int(lateEvents)
+
+	// Events of each complete second in the retention before the watermark, the second of the watermark is skipped while it is counted
+	watermark := ts.Watermark.Now()
+	counts := ts.completeCounts(ctx, watermark.Truncate(RATE_BUCKET))
+
+	lastMinute := counts[len(counts)-int(MATERIALIZED_WINDOW/RATE_BUCKET):]
+	rates := make(map[string]RateStats, len(ts.Windows))
+	for _, window := range ts.Windows {
+		seconds := int(window / RATE_BUCKET)
+		rates[windowName(window)] = RateStats{
+			SMAPerSecond:  roundRate(simpleMovingAverage(counts[len(counts)-seconds:])),
+			EWMAPerSecond: roundRate(exponentialMovingAverage(counts[len(counts)-EWMA_HISTORY*seconds:], window)),
+		}
 	}
 
-	// Events per minute
-	eventsPerMinute, err := ts.RedisClient.ZCount(ctx, EVENTS_PER_MINUTE, fmt.Sprintf("%f", currentTimestamp-60), fmt.Sprintf("%f", currentTimestamp)).Result()
-	if err != nil {
-		log.Fatalf("Error getting events per minute: %v", err)
+	return &TimeStats{
+		Windows:            ts.Windows,
+		TotalEvents:        totalEvents,
+		LateEvents:         lateEvents,
+		WatermarkTime:      watermark,
+		EventsPerMinute:    roundRate(simpleMovingAverage(lastMinute) * float64(MATERIALIZED_WINDOW/RATE_BUCKET)),
+		MovingAvgPerSecond: roundRate(simpleMovingAverage(lastMinute)),
+		Rates:              rates,
 	}
+}
 
-	// Moving average events per second
-	eventsInLastMinute, err := ts.RedisClient.LLen(ctx, MOVING_AVG_PER_SECOND).Result()
-	if err != nil {
-		log.Fatalf("Error getting events in last minute: %v", err)
+// Returns the counts of the seconds in the retention before end, only the seconds after the cached counts and the refresh window are read from the store
+func (ts *TimeStats) completeCounts(ctx context.Context, end time.Time) []int64 {
+	ts.countsMu.Lock()
+	defer ts.countsMu.Unlock()
+
+	retention := ts.retention()
+	fresh := end.Sub(ts.countsEnd) + RATE_REFRESH_WINDOW
+	if ts.counts == nil || end.Before(ts.countsEnd) || fresh >= retention {
+		ts.counts = ts.Store.Counts(ctx, end, retention)
+		ts.countsEnd = end
+		return ts.counts
 	}
-	movingAvgPerSecond := math.Round(float64(eventsInLastMinute)/60.*100) / 100
-
-	ts.TotalEvents = totalEvents
-	ts.EventsPerMinute = eventsPerMinute
-	ts.MovingAvgPerSecond = movingAvgPerSecond
-}
 
-// Increment total events
-func (ts *TimeStats) IncrementTotalEvents(ctx context.Context) {
-	ts.RedisClient.Incr(ctx, TOTAL_EVENTS)
-}
+	// The previous counts are kept by the callers, so the counts are copied into a new slice
+	kept := ts.counts[int(end.Sub(ts.countsEnd)/RATE_BUCKET) : len(ts.counts)-int(RATE_REFRESH_WINDOW/RATE_BUCKET)]
+	counts := make([]int64, 0, len(ts.counts))
+	counts = append(counts, kept...)
+	counts = append(counts, ts.Store.Counts(ctx, end, fresh)...)
 
-// Add timestamp to sorted set
-func (ts *TimeStats) AddEventPerMinute(ctx context.Context, timestamp float64, id int) {
-	ts.RedisClient.ZAdd(ctx, EVENTS_PER_MINUTE, &redis.Z{
-		Score:  timestamp,
-		Member: id,
-	})
+	ts.counts = counts
+	ts.countsEnd = end
+	return counts
 }
 
-// Add timestamp to list and trim to last 60 seconds
-func (ts *TimeStats) AddMovingAvgPerSecond(ctx context.Context, timestamp float64) {
-	ts.RedisClient.LPush(ctx, MOVING_AVG_PER_SECOND, timestamp)
-	ts.RedisClient.LTrim(ctx, MOVING_AVG_PER_SECOND, 0, 59)
+// Drops the cached counts, the store was changed by a reset or restore
+func (ts *TimeStats) resetCounts() {
+	ts.countsMu.Lock()
+	defer ts.countsMu.Unlock()
+	ts.counts = nil
 }
 
-func (ts *TimeStats) ResetRedisKeys(ctx context.Context) {
-
-	// Reset INCR TOTAL_EVENTS key to 0
-	if err := ts.RedisClient.Set(ctx, TOTAL_EVENTS, 0, 0).Err(); err != nil {
-		log.Fatalf("Error resetting total events: %v", err)
-	}
-
-	// Delete ZADD EVENTS_PER_MINUTE sorted set
-	if err := ts.RedisClient.Del(ctx, EVENTS_PER_MINUTE).Err(); err != nil {
-		log.Fatalf("Error deleting events per minute: %v", err)
+// AddEvent counts the event in the total and in the second of its time, late events are only counted as late
+func (ts *TimeStats) AddEvent(ctx context.Context, createdAt time.Time) {
+	eventTime, ok := ts.Watermark.Observe(createdAt)
+	if !ok {
+		ts.lateEvents.Add(1)
+		return
 	}
+	ts.Store.AddEvent(ctx, eventTime)
+}
 
-	// Delete LPUSH MOVING_AVG_PER_SECOND list
-	if err := ts.RedisClient.Del(ctx, MOVING_AVG_PER_SECOND).Err(); err != nil {
-		log.Fatalf("Error deleting event list: %v", err)
-	}
-
-	log.Println("Redis keys reset successfully")
-
+// Reset clears the counters of the previous run, the watermark starts with the process
+func (ts *TimeStats) Reset(ctx context.Context) {
+	ts.Store.Reset(ctx)
+	ts.lateEvents.Store(0)
+	ts.resetCounts()
 }
 
 func (ts *TimeStats) String() string {
@@ -0,0 +1,91 @@
+package statistics
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// Each slot of the ring packs the second (high 32 bits) and its event count (low 32 bits),
+// so both are updated together with one compare-and-swap
+const (
+	SLOT_SECOND_SHIFT = 32
+	SLOT_COUNT_MASK   = 1<<SLOT_SECOND_SHIFT - 1
+)
+
+// MemoryTimeStatsStore keeps the per-second counters in a lock-free ring buffer, a slot is reused by a later second
+type MemoryTimeStatsStore struct {
+	totalEvents atomic.Int64
+	slots       []atomic.Uint64
+}
+
+func NewMemoryTimeStatsStore(retention time.Duration) *MemoryTimeStatsStore {
+	return &MemoryTimeStatsStore{
+		// One more slot for the current second
+		slots: make([]atomic.Uint64, int(retention/RATE_BUCKET)+1),
+	}
+}
+
+func (ms *MemoryTimeStatsStore) AddEvent(ctx context.Context, createdAt time.Time) {
+	ms.totalEvents.Add(1)
+
+	second := uint64(createdAt.Unix())
+	slot := &ms.slots[second%uint64(len(ms.slots))]
+	for {
+		old := slot.Load()
+		oldSecond := old >> SLOT_SECOND_SHIFT
+
+		var next uint64
+		switch {
+		case oldSecond == second:
+			next = old + 1
+		case oldSecond < second:
+			next = second<<SLOT_SECOND_SHIFT | 1
+		default:
+			// The slot holds a later second, the event is older than the retention
+			return
+		}
+
+		if slot.CompareAndSwap(old, next) {
+			return
+		}
+	}
+}
+
+func (ms *MemoryTimeStatsStore) TotalEvents(ctx context.Context) int {
+	return int(ms.totalEvents.Load())
+}
+
+func (ms *MemoryTimeStatsStore) Counts(ctx context.Context, end time.Time, duration time.Duration) []int64 {
+	seconds := int(duration / RATE_BUCKET)
+	start := uint64(end.Unix() - int64(seconds))
+
+	counts := make([]int64, seconds)
+	for i := range counts {
+		second := start + uint64(i)
+		value := ms.slots[second%uint64(len(ms.slots))].Load()
+		if value>>SLOT_SECOND_SHIFT == second {
+			counts[i] = int64(value & SLOT_COUNT_MASK)
+		}
+	}
+	return counts
+}
+
+func (ms *MemoryTimeStatsStore) Reset(ctx context.Context) {
+	ms.totalEvents.Store(0)
+	for i := range ms.slots {
+		ms.slots[i].Store(0)
+	}
+}
+
+func (ms *MemoryTimeStatsStore) Restore(ctx context.Context, totalEvents int, end time.Time, counts []int64) {
+	ms.totalEvents.Store(int64(totalEvents))
+
+	start := uint64(end.Unix() - int64(len(counts)))
+	for i, count := range counts {
+		if count > 0 {
+			second := start + uint64(i)
+			ms.slots[second%uint64(len(ms.slots))].Store(second<<SLOT_SECOND_SHIFT | uint64(count)&SLOT_COUNT_MASK)
+		}
+	}
+}
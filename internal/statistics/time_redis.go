@@ -0,0 +1,137 @@
+package statistics
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	rds "github.com/Bitstarz-eng/event-processing-challenge/internal/redis"
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	TOTAL_EVENTS = "total_events"
+
+	// Prefix of the per-second event counters, e.g. events_per_second:1700000000
+	EVENTS_PER_SECOND = "events_per_second"
+)
+
+// RedisTimeStatsStore keeps the counters in Redis, the per-second counters expire once the watermark is the retention past them
+type RedisTimeStatsStore struct {
+	RedisClient *redis.Client
+	retention   time.Duration
+	watermark   *Watermark
+}
+
+func NewRedisTimeStatsStore(retention time.Duration, watermark *Watermark) *RedisTimeStatsStore {
+	return &RedisTimeStatsStore{
+		RedisClient: rds.GetRedisClient(),
+		retention:   retention,
+		watermark:   watermark,
+	}
+}
+
+func (rs *RedisTimeStatsStore) AddEvent(ctx context.Context, createdAt time.Time) {
+	second := createdAt.Unix()
+	ttl, ok := bucketTTL(second, rs.watermark.Now(), rs.retention)
+	_, err := rs.RedisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Incr(ctx, TOTAL_EVENTS)
+		if ok {
+			key := bucketKey(second)
+			pipe.Incr(ctx, key)
+			pipe.Expire(ctx, key, ttl)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Error adding event to time stats: %v", err)
+	}
+}
+
+func (rs *RedisTimeStatsStore) TotalEvents(ctx context.Context) int {
+	totalEvents, err := rs.RedisClient.Get(ctx, TOTAL_EVENTS).Int()
+	if err != nil && err != redis.Nil {
+		log.Fatalf("Error getting total events: %v", err)
+	}
+	return totalEvents
+}
+
+func (rs *RedisTimeStatsStore) Counts(ctx context.Context, end time.Time, duration time.Duration) []int64 {
+	seconds := int(duration / RATE_BUCKET)
+	start := end.Unix() - int64(seconds)
+
+	keys := make([]string, seconds)
+	for i := range keys {
+		keys[i] = bucketKey(start + int64(i))
+	}
+
+	values, err := rs.RedisClient.MGet(ctx, keys...).Result()
+	if err != nil {
+		log.Fatalf("Error getting events per second: %v", err)
+	}
+
+	counts := make([]int64, seconds)
+	for i, value := range values {
+		if value == nil {
+			continue
+		}
+		var count int64
+		if _, err := fmt.Sscan(value.(string), &count); err != nil {
+			log.Printf("Invalid events per second counter %s: %v", keys[i], err)
+		}
+		counts[i] = count
+	}
+	return counts
+}
+
+func (rs *RedisTimeStatsStore) Reset(ctx context.Context) {
+
+	// Reset INCR TOTAL_EVENTS key to 0
+	if err := rs.RedisClient.Set(ctx, TOTAL_EVENTS, 0, 0).Err(); err != nil {
+		log.Fatalf("Error resetting total events: %v", err)
+	}
+
+	// Delete the per-second counters
+	iter := rs.RedisClient.Scan(ctx, 0, EVENTS_PER_SECOND+":*", 1000).Iterator()
+	for iter.Next(ctx) {
+		if err := rs.RedisClient.Del(ctx, iter.Val()).Err(); err != nil {
+			log.Fatalf("Error deleting events per second: %v", err)
+		}
+	}
+	if err := iter.Err(); err != nil {
+		log.Fatalf("Error scanning events per second: %v", err)
+	}
+
+	log.Println("Redis keys reset successfully")
+
+}
+
+func (rs *RedisTimeStatsStore) Restore(ctx context.Context, totalEvents int, end time.Time, counts []int64) {
+	start := end.Unix() - int64(len(counts))
+	watermark := rs.watermark.Now()
+	_, err := rs.RedisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, TOTAL_EVENTS, totalEvents, 0)
+		for i, count := range counts {
+			second := start + int64(i)
+			if ttl, ok := bucketTTL(second, watermark, rs.retention); ok && count > 0 {
+				pipe.Set(ctx, bucketKey(second), count, ttl)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Fatalf("Error restoring time stats: %v", err)
+	}
+}
+
+// bucketTTL returns how long the counter of the second is kept: until the watermark is the retention past the end of the second.
+// The keys are named by the event time, so their expiry follows the watermark rather than the wall clock. False if the second is already expired.
+func bucketTTL(second int64, watermark time.Time, retention time.Duration) (time.Duration, bool) {
+	ttl := time.Unix(second, 0).Add(RATE_BUCKET + retention).Sub(watermark)
+	return ttl, ttl > 0
+}
+
+func bucketKey(second int64) string {
+	return fmt.Sprintf("%s:%d", EVENTS_PER_SECOND, second)
+}
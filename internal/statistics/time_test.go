@@ -0,0 +1,121 @@
+package statistics
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// countingTimeStore keeps the counts in memory and records the duration of every Counts read
+type countingTimeStore struct {
+	*MemoryTimeStatsStore
+	reads []time.Duration
+}
+
+func (cs *countingTimeStore) Counts(ctx context.Context, end time.Time, duration time.Duration) []int64 {
+	cs.reads = append(cs.reads, duration)
+	return cs.MemoryTimeStatsStore.Counts(ctx, end, duration)
+}
+
+func TestTimeStatsReadOnlyTheNewSeconds(t *testing.T) {
+	t.Setenv("TIME_STATS_STORE", TIME_STATS_STORE_MEMORY)
+	t.Setenv("ALLOWED_LATENESS", "0s")
+	ctx := context.Background()
+
+	ts := NewTimeStats()
+	store := &countingTimeStore{MemoryTimeStatsStore: NewMemoryTimeStatsStore(ts.retention())}
+	ts.Store = store
+
+	start := time.Now().Truncate(RATE_BUCKET).Add(-time.Hour)
+	for i := 0; i < 120; i++ {
+		ts.AddEvent(ctx, start.Add(time.Duration(i)*time.Second))
+	}
+	first := ts.CalculateTimeStats()
+
+	for i := 120; i < 130; i++ {
+		ts.AddEvent(ctx, start.Add(time.Duration(i)*time.Second))
+		ts.AddEvent(ctx, start.Add(time.Duration(i)*time.Second))
+	}
+	second := ts.CalculateTimeStats()
+
+	if want := []time.Duration{ts.retention(), 10*time.Second + RATE_REFRESH_WINDOW}; !reflect.DeepEqual(store.reads, want) {
+		t.Fatalf("read durations = %v, want %v", store.reads, want)
+	}
+	end := ts.Watermark.Now().Truncate(RATE_BUCKET)
+	if cached, read := ts.completeCounts(ctx, end), store.MemoryTimeStatsStore.Counts(ctx, end, ts.retention()); !reflect.DeepEqual(cached, read) {
+		t.Fatal("cached counts differ from the store")
+	}
+	// The second of the watermark is incomplete, the last minute before it has 9 seconds of two events and 51 of one
+	if first.EventsPerMinute != 60 || second.EventsPerMinute != 69 {
+		t.Fatalf("events per minute = %v then %v, want 60 then 69", first.EventsPerMinute, second.EventsPerMinute)
+	}
+
+	ts.Reset(ctx)
+	ts.CalculateTimeStats()
+	if last := store.reads[len(store.reads)-1]; last != ts.retention() {
+		t.Fatalf("read after the reset = %v, want the whole retention", last)
+	}
+}
+
+func TestBucketTTLFollowsTheWatermark(t *testing.T) {
+	watermark := time.Unix(1000, 0)
+	for _, tc := range []struct {
+		second int64
+		want   time.Duration
+		ok     bool
+	}{
+		{1000, time.Hour + time.Second, true},
+		{1010, time.Hour + 11*time.Second, true},
+		{1000 - 3600, time.Second, true},
+		{1000 - 3601, 0, false},
+	} {
+		ttl, ok := bucketTTL(tc.second, watermark, time.Hour)
+		if ok != tc.ok || (ok && ttl != tc.want) {
+			t.Errorf("TTL of second %d = %v %v, want %v %v", tc.second, ttl, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestMemoryTimeStatsStoreRing(t *testing.T) {
+	ctx := context.Background()
+	ms := NewMemoryTimeStatsStore(10 * time.Second)
+	start := time.Unix(1700000000, 0)
+
+	// Concurrent events of the same seconds are all counted
+	var wg sync.WaitGroup
+	for worker := 0; worker < 4; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for second := 0; second < 5; second++ {
+				for i := 0; i < 100; i++ {
+					ms.AddEvent(ctx, start.Add(time.Duration(second)*time.Second))
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got, want := ms.Counts(ctx, start.Add(5*time.Second), 5*time.Second), []int64{400, 400, 400, 400, 400}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("counts = %v, want %v", got, want)
+	}
+
+	// A later second reuses the slot of the first second, then an event of the first second is too old for the ring
+	later := start.Add(11 * time.Second)
+	ms.AddEvent(ctx, later)
+	ms.AddEvent(ctx, start)
+	if got, want := ms.Counts(ctx, later.Add(time.Second), 12*time.Second), []int64{0, 400, 400, 400, 400, 0, 0, 0, 0, 0, 0, 1}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("counts after the ring wrapped = %v, want %v", got, want)
+	}
+	if total := ms.TotalEvents(ctx); total != 2002 {
+		t.Fatalf("total events = %d, want 2002", total)
+	}
+
+	ms.Reset(ctx)
+	ms.Restore(ctx, 7, start.Add(3*time.Second), []int64{1, 0, 6})
+	if got, want := ms.Counts(ctx, start.Add(3*time.Second), 3*time.Second), []int64{1, 0, 6}; !reflect.DeepEqual(got, want) || ms.TotalEvents(ctx) != 7 {
+		t.Fatalf("restored counts = %v and total %d, want %v and 7", got, ms.TotalEvents(ctx), want)
+	}
+}
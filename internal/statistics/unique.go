@@ -0,0 +1,207 @@
+package statistics
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/Bitstarz-eng/event-processing-challenge/internal/casino"
+	"github.com/Bitstarz-eng/event-processing-challenge/internal/config"
+)
+
+// Backends of the unique players sketches, configured by HLL_BACKEND
+const (
+	HLL_BACKEND_MEMORY = "memory"
+	HLL_BACKEND_REDIS  = "redis"
+)
+
+// Keys of the sketches: all players, players of a game, of a currency and of a minute of the event time
+const (
+	UNIQUE_ALL      = "all"
+	UNIQUE_GAME     = "game"
+	UNIQUE_CURRENCY = "currency"
+	UNIQUE_MINUTE   = "minute"
+)
+
+// UniqueStore keeps a HyperLogLog sketch of the players of each key
+type UniqueStore interface {
+	// Add counts the player in the sketch of each key
+	Add(ctx context.Context, playerID int, keys ...string)
+
+	// Count returns the estimated number of distinct players in the union of the keys
+	Count(ctx context.Context, keys ...string) int64
+
+	Delete(ctx context.Context, keys ...string)
+	Reset(ctx context.Context)
+
+	// Sketches returns the encoded sketch of each key, Restore loads them
+	Sketches(ctx context.Context) map[string][]byte
+	Restore(ctx context.Context, sketches map[string][]byte) error
+}
+
+// UniqueStats is the unique players part of the /materialized API, the counts are approximate
+type UniqueStats struct {
+	Players     int64            `json:"players"`
+	PerCurrency map[string]int64 `json:"per_currency"`
+	PerWindow   map[string]int64 `json:"per_window"`
+}
+
+// UniquePlayers counts the distinct players overall, per game, per currency and per minute for the windows
+type UniquePlayers struct {
+	Store     UniqueStore
+	backend   string
+	location  *time.Location
+	watermark *Watermark
+
+	mu         sync.Mutex
+	minutes    map[int64]struct{}  // Minutes with a sketch in the store
+	currencies map[string]struct{} // Currencies with a sketch in the store
+	expiredTo  int64               // Minutes before it are expired
+}
+
+func NewUniquePlayers() *UniquePlayers {
+	up := &UniquePlayers{
+		backend:    config.GetString("HLL_BACKEND", HLL_BACKEND_MEMORY),
+		location:   loadTimezone(),
+		watermark:  NewWatermark(),
+		minutes:    make(map[int64]struct{}),
+		currencies: make(map[string]struct{}),
+	}
+
+	switch up.backend {
+	case HLL_BACKEND_MEMORY:
+		up.Store = NewMemoryUniqueStore()
+	case HLL_BACKEND_REDIS:
+		up.Store = NewRedisUniqueStore()
+	default:
+		log.Fatalf("Unknown HLL_BACKEND %q", up.backend)
+	}
+	return up
+}
+
+// HandleEvent counts the player of the event, expired events are not counted in the windows.
+// Late events are counted in the windows that cover their minute.
+func (up *UniquePlayers) HandleEvent(event *casino.Event) {
+	keys := []string{UNIQUE_ALL}
+	if event.GameID != 0 {
+		keys = append(keys, gameKey(event.GameID))
+	}
+
+	up.mu.Lock()
+	if event.Currency != "" {
+		keys = append(keys, currencyKey(event.Currency))
+		up.currencies[event.Currency] = struct{}{}
+	}
+	eventTime, _ := up.watermark.Observe(event.CreatedAt)
+	now := up.watermark.Now()
+	if !eventTime.Before(now.Add(-WINDOW_RETENTION)) {
+		minute := toMinute(eventTime)
+		keys = append(keys, minuteKey(minute))
+		up.minutes[minute] = struct{}{}
+	}
+	up.expire(now)
+	up.mu.Unlock()
+
+	up.Store.Add(context.Background(), event.PlayerID, keys...)
+}
+
+// Game returns the estimated number of distinct players of the game
+func (up *UniquePlayers) Game(gameId int) int64 {
+	return up.Store.Count(context.Background(), gameKey(gameId))
+}
+
+// Window returns the estimated number of distinct players in the window
+func (up *UniquePlayers) Window(window string) int64 {
+	ctx := context.Background()
+	if window == WINDOW_ALL {
+		return up.Store.Count(ctx, UNIQUE_ALL)
+	}
+
+	up.mu.Lock()
+	now := up.watermark.Now()
+	from, to := toMinute(windowStart(window, now, up.location)), toMinute(now)
+	keys := make([]string, 0, len(up.minutes))
+	for minute := range up.minutes {
+		if minute >= from && minute <= to {
+			keys = append(keys, minuteKey(minute))
+		}
+	}
+	up.mu.Unlock()
+
+	if len(keys) == 0 {
+		return 0
+	}
+	return up.Store.Count(ctx, keys...)
+}
+
+// Stats returns the estimated number of distinct players overall, per currency and per window
+func (up *UniquePlayers) Stats() *UniqueStats {
+	ctx := context.Background()
+
+	up.mu.Lock()
+	currencies := make([]string, 0, len(up.currencies))
+	for currency := range up.currencies {
+		currencies = append(currencies, currency)
+	}
+	up.mu.Unlock()
+
+	stats := &UniqueStats{
+		Players:     up.Store.Count(ctx, UNIQUE_ALL),
+		PerCurrency: make(map[string]int64, len(currencies)),
+		PerWindow:   make(map[string]int64, len(Windows)-1),
+	}
+	for _, currency := range currencies {
+		stats.PerCurrency[currency] = up.Store.Count(ctx, currencyKey(currency))
+	}
+	for _, window := range Windows {
+		if window != WINDOW_ALL {
+			stats.PerWindow[window] = up.Window(window)
+		}
+	}
+	return stats
+}
+
+// Reset clears the sketches of the previous run
+func (up *UniquePlayers) Reset(ctx context.Context) {
+	up.mu.Lock()
+	defer up.mu.Unlock()
+
+	up.Store.Reset(ctx)
+	up.minutes = make(map[int64]struct{})
+	up.currencies = make(map[string]struct{})
+	up.expiredTo = 0
+}
+
+// Drop the sketches of the minutes before the retention
+func (up *UniquePlayers) expire(now time.Time) {
+	oldest := toMinute(now.Add(-WINDOW_RETENTION))
+	if oldest <= up.expiredTo {
+		return
+	}
+	up.expiredTo = oldest
+
+	var expired []string
+	for minute := range up.minutes {
+		if minute < oldest {
+			expired = append(expired, minuteKey(minute))
+			delete(up.minutes, minute)
+		}
+	}
+	if len(expired) > 0 {
+		up.Store.Delete(context.Background(), expired...)
+	}
+}
+
+func gameKey(gameId int) string {
+	return fmt.Sprintf("%s:%d", UNIQUE_GAME, gameId)
+}
+
+func currencyKey(currency string) string {
+	return UNIQUE_CURRENCY + ":" + currency
+}
+
+func minuteKey(minute int64) string {
+	return fmt.Sprintf("%s:%d", UNIQUE_MINUTE, minute)
+}
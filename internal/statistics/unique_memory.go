@@ -0,0 +1,95 @@
+package statistics
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryUniqueStore keeps a HyperLogLog sketch of each key in memory
+type MemoryUniqueStore struct {
+	mu       sync.Mutex
+	sketches map[string]*HyperLogLog
+}
+
+func NewMemoryUniqueStore() *MemoryUniqueStore {
+	return &MemoryUniqueStore{
+		sketches: make(map[string]*HyperLogLog),
+	}
+}
+
+func (ms *MemoryUniqueStore) Add(ctx context.Context, playerID int, keys ...string) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	for _, key := range keys {
+		sketch, ok := ms.sketches[key]
+		if !ok {
+			sketch = NewHyperLogLog()
+			ms.sketches[key] = sketch
+		}
+		sketch.Add(playerID)
+	}
+}
+
+func (ms *MemoryUniqueStore) Count(ctx context.Context, keys ...string) int64 {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	if len(keys) == 1 {
+		if sketch, ok := ms.sketches[keys[0]]; ok {
+			return sketch.Count()
+		}
+		return 0
+	}
+
+	union := NewHyperLogLog()
+	for _, key := range keys {
+		if sketch, ok := ms.sketches[key]; ok {
+			union.Merge(sketch)
+		}
+	}
+	return union.Count()
+}
+
+func (ms *MemoryUniqueStore) Delete(ctx context.Context, keys ...string) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	for _, key := range keys {
+		delete(ms.sketches, key)
+	}
+}
+
+func (ms *MemoryUniqueStore) Reset(ctx context.Context) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	ms.sketches = make(map[string]*HyperLogLog)
+}
+
+func (ms *MemoryUniqueStore) Sketches(ctx context.Context) map[string][]byte {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	sketches := make(map[string][]byte, len(ms.sketches))
+	for key, sketch := range ms.sketches {
+		sketches[key] = sketch.Bytes()
+	}
+	return sketches
+}
+
+func (ms *MemoryUniqueStore) Restore(ctx context.Context, sketches map[string][]byte) error {
+	restored := make(map[string]*HyperLogLog, len(sketches))
+	for key, registers := range sketches {
+		sketch, err := HyperLogLogFromBytes(registers)
+		if err != nil {
+			return err
+		}
+		restored[key] = sketch
+	}
+
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.sketches = restored
+	return nil
+}
@@ -0,0 +1,107 @@
+package statistics
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	rds "github.com/Bitstarz-eng/event-processing-challenge/internal/redis"
+	"github.com/go-redis/redis/v8"
+)
+
+// Prefix of the HyperLogLog keys, e.g. unique_players:game:100
+const UNIQUE_PLAYERS = "unique_players"
+
+// RedisUniqueStore keeps the sketches in Redis with PFADD and PFCOUNT
+type RedisUniqueStore struct {
+	RedisClient *redis.Client
+}
+
+func NewRedisUniqueStore() *RedisUniqueStore {
+	return &RedisUniqueStore{
+		RedisClient: rds.GetRedisClient(),
+	}
+}
+
+func (rs *RedisUniqueStore) Add(ctx context.Context, playerID int, keys ...string) {
+	_, err := rs.RedisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, key := range keys {
+			pipe.PFAdd(ctx, uniqueKey(key), playerID)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Error adding player to unique players: %v", err)
+	}
+}
+
+func (rs *RedisUniqueStore) Count(ctx context.Context, keys ...string) int64 {
+	redisKeys := make([]string, len(keys))
+	for i, key := range keys {
+		redisKeys[i] = uniqueKey(key)
+	}
+
+	count, err := rs.RedisClient.PFCount(ctx, redisKeys...).Result()
+	if err != nil {
+		log.Printf("Error counting unique players: %v", err)
+	}
+	return count
+}
+
+func (rs *RedisUniqueStore) Delete(ctx context.Context, keys ...string) {
+	redisKeys := make([]string, len(keys))
+	for i, key := range keys {
+		redisKeys[i] = uniqueKey(key)
+	}
+
+	if err := rs.RedisClient.Del(ctx, redisKeys...).Err(); err != nil {
+		log.Printf("Error deleting unique players: %v", err)
+	}
+}
+
+func (rs *RedisUniqueStore) Reset(ctx context.Context) {
+	iter := rs.RedisClient.Scan(ctx, 0, UNIQUE_PLAYERS+":*", 1000).Iterator()
+	for iter.Next(ctx) {
+		if err := rs.RedisClient.Del(ctx, iter.Val()).Err(); err != nil {
+			log.Fatalf("Error deleting unique players: %v", err)
+		}
+	}
+	if err := iter.Err(); err != nil {
+		log.Fatalf("Error scanning unique players: %v", err)
+	}
+}
+
+// Sketches returns the Redis encoding of the sketches, GET returns it and SET restores it
+func (rs *RedisUniqueStore) Sketches(ctx context.Context) map[string][]byte {
+	sketches := make(map[string][]byte)
+	iter := rs.RedisClient.Scan(ctx, 0, UNIQUE_PLAYERS+":*", 1000).Iterator()
+	for iter.Next(ctx) {
+		sketch, err := rs.RedisClient.Get(ctx, iter.Val()).Bytes()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			log.Printf("Error getting unique players %s: %v", iter.Val(), err)
+			continue
+		}
+		sketches[strings.TrimPrefix(iter.Val(), UNIQUE_PLAYERS+":")] = sketch
+	}
+	if err := iter.Err(); err != nil {
+		log.Printf("Error scanning unique players: %v", err)
+	}
+	return sketches
+}
+
+func (rs *RedisUniqueStore) Restore(ctx context.Context, sketches map[string][]byte) error {
+	_, err := rs.RedisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for key, sketch := range sketches {
+			pipe.Set(ctx, uniqueKey(key), sketch, 0)
+		}
+		return nil
+	})
+	return err
+}
+
+func uniqueKey(key string) string {
+	return UNIQUE_PLAYERS + ":" + key
+}
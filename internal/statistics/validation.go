@@ -0,0 +1,45 @@
+package statistics
+
+import (
+	"encoding/json"
+	"log"
+	"sync"
+)
+
+// ValidationStats counts the invalid events per broken validation rule
+type ValidationStats struct {
+	mu    sync.Mutex
+	rules map[string]int64
+}
+
+func NewValidationStats() *ValidationStats {
+	return &ValidationStats{
+		rules: make(map[string]int64),
+	}
+}
+
+func (vs *ValidationStats) Add(rule string) {
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+	vs.rules[rule]++
+}
+
+// Snapshot returns a copy of the counters
+func (vs *ValidationStats) Snapshot() map[string]int64 {
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+
+	rules := make(map[string]int64, len(vs.rules))
+	for rule, count := range vs.rules {
+		rules[rule] = count
+	}
+	return rules
+}
+
+func (vs *ValidationStats) String() string {
+	validationStats, err := json.MarshalIndent(vs.Snapshot(), "", "  ")
+	if err != nil {
+		log.Println("Error marshaling ValidationStats to JSON:", err)
+	}
+	return string(validationStats)
+}
@@ -0,0 +1,101 @@
+package statistics
+
+import (
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/Bitstarz-eng/event-processing-challenge/internal/config"
+)
+
+// Time semantics of the windowed statistics, configured by TIME_SEMANTICS
+const (
+	// Events are windowed by their created_at, the windows end at the watermark
+	TIME_SEMANTICS_EVENT = "event"
+
+	// Events are windowed by the time they are handled, the windows end now
+	TIME_SEMANTICS_PROCESSING = "processing"
+)
+
+// Default delay of the watermark behind the latest event, configured by ALLOWED_LATENESS
+const DEFAULT_ALLOWED_LATENESS = 5 * time.Second
+
+// Watermark tracks the progress of the event time: the latest created_at minus the allowed lateness.
+// Windows before the watermark are complete, events created before it are late and counted separately by each statistic.
+// Each aggregator has its own watermark, advanced only by the events it handles.
+type Watermark struct {
+	semantics       string
+	allowedLateness time.Duration
+
+	maxEventTime atomic.Int64 // Unix nanoseconds
+	lastEventAt  atomic.Int64 // Unix nanoseconds of the wall clock
+}
+
+func NewWatermark() *Watermark {
+	semantics := config.GetString("TIME_SEMANTICS", TIME_SEMANTICS_EVENT)
+	if semantics != TIME_SEMANTICS_EVENT && semantics != TIME_SEMANTICS_PROCESSING {
+		log.Fatalf("Unknown TIME_SEMANTICS %q", semantics)
+	}
+
+	allowedLateness := config.GetDuration("ALLOWED_LATENESS", DEFAULT_ALLOWED_LATENESS)
+	if allowedLateness < 0 {
+		log.Fatalf("ALLOWED_LATENESS must not be negative, got %v", allowedLateness)
+	}
+
+	return &Watermark{
+		semantics:       semantics,
+		allowedLateness: allowedLateness,
+	}
+}
+
+// Observe returns the time of the event in the configured semantics, false if the event is late
+func (w *Watermark) Observe(createdAt time.Time) (time.Time, bool) {
+	if w.semantics == TIME_SEMANTICS_PROCESSING {
+		return time.Now(), true
+	}
+
+	w.lastEventAt.Store(time.Now().UnixNano())
+	w.advance(createdAt.UnixNano())
+
+	return createdAt, !createdAt.Before(w.Now())
+}
+
+// Now returns the end of the complete windows: the watermark for the event time, the current time for the processing time.
+// The watermark only advances with the events, before the first event it is the current time.
+func (w *Watermark) Now() time.Time {
+	latest := w.maxEventTime.Load()
+	if w.semantics == TIME_SEMANTICS_PROCESSING || latest == 0 {
+		return time.Now()
+	}
+	return time.Unix(0, latest).Add(-w.allowedLateness)
+}
+
+// Latest returns the latest event time for the event time, the current time for the processing time or before the first event
+func (w *Watermark) Latest() time.Time {
+	latest := w.maxEventTime.Load()
+	if w.semantics == TIME_SEMANTICS_PROCESSING || latest == 0 {
+		return time.Now()
+	}
+	return time.Unix(0, latest)
+}
+
+// IdleNow returns the watermark advanced by the wall-clock time since the latest event,
+// so the statistics which expire keep progressing while no events arrive
+func (w *Watermark) IdleNow() time.Time {
+	now := w.Now()
+	lastEventAt := w.lastEventAt.Load()
+	if w.semantics == TIME_SEMANTICS_PROCESSING || lastEventAt == 0 {
+		return now
+	}
+	return now.Add(time.Since(time.Unix(0, lastEventAt)))
+}
+
+// Moves the watermark to the event time, an older time leaves it as it is
+func (w *Watermark) advance(eventTime int64) {
+	for {
+		latest := w.maxEventTime.Load()
+		if eventTime <= latest || w.maxEventTime.CompareAndSwap(latest, eventTime) {
+			return
+		}
+	}
+}
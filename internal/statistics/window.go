@@ -0,0 +1,263 @@
+package statistics
+
+import (
+	"log"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+	_ "time/tzdata" // The timezone of the calendar windows is available without the system timezone database
+
+	"github.com/Bitstarz-eng/event-processing-challenge/internal/casino"
+	"github.com/Bitstarz-eng/event-processing-challenge/internal/config"
+)
+
+// Windows of the player statistics, WINDOW_ALL is the lifetime of the process
+const (
+	WINDOW_ALL   = "all"
+	WINDOW_5M    = "5m"
+	WINDOW_1H    = "1h"
+	WINDOW_24H   = "24h"
+	WINDOW_TODAY = "today" // Current day in the STATS_TIMEZONE
+)
+
+var Windows = []string{
+	WINDOW_ALL,
+	WINDOW_5M,
+	WINDOW_1H,
+	WINDOW_24H,
+	WINDOW_TODAY,
+}
+
+const (
+	// The windowed statistics are aggregated per minute of the event time (or processing time, see TIME_SEMANTICS)
+	WINDOW_BUCKET = time.Minute
+
+	// Buckets older than the longest window are expired, a day is up to 25h long with daylight saving time
+	WINDOW_RETENTION = 25 * time.Hour
+)
+
+// IsWindow returns true if the window is known
+func IsWindow(window string) bool {
+	for _, w := range Windows {
+		if w == window {
+			return true
+		}
+	}
+	return false
+}
+
+// loadTimezone returns the location of STATS_TIMEZONE (default UTC), used for the calendar windows
+func loadTimezone() *time.Location {
+	name := config.GetString("STATS_TIMEZONE", "UTC")
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		log.Fatalf("Invalid STATS_TIMEZONE %q: %v", name, err)
+	}
+	return location
+}
+
+type windowBucket struct {
+	minute int64 // Unix time in minutes
+	values map[string]int64
+}
+
+// PlayerWindows keeps the leaderboard metrics of each player per minute, so they can be summed over any window.
+// The windows end at the watermark, the buckets after it are summed once the watermark passes them.
+type PlayerWindows struct {
+	mu         sync.Mutex
+	location   *time.Location
+	watermark  *Watermark
+	buckets    map[int][]*windowBucket // Player ID -> buckets ordered by minute
+	lateEvents atomic.Int64
+}
+
+func NewPlayerWindows(location *time.Location, watermark *Watermark) *PlayerWindows {
+	return &PlayerWindows{
+		location:  location,
+		watermark: watermark,
+		buckets:   make(map[int][]*windowBucket),
+	}
+}
+
+// Record adds the event to the bucket of its minute, expired events are ignored.
+// Late events are counted and still added, so they are summed in the windows that cover their minute but not in the windows that start after it.
+func (pw *PlayerWindows) Record(event *casino.Event) {
+	eventTime, ok := pw.watermark.Observe(event.CreatedAt)
+	if !ok {
+		pw.lateEvents.Add(1)
+	}
+
+	values := make(map[string]int64)
+	switch event.Type {
+	case casino.BET:
+		values[LEADERBOARD_BET_COUNT] = 1
+		values[LEADERBOARD_BET_AMOUNT] = int64(event.AmountEUR)
+		values[LEADERBOARD_NET_RESULT] = -int64(event.AmountEUR)
+	case casino.DEPOSIT:
+		values[LEADERBOARD_DEPOSIT_SUM] = int64(event.AmountEUR)
+	case casino.WIN:
+		values[LEADERBOARD_NET_RESULT] = int64(event.AmountEUR)
+	}
+	if event.HasWon {
+		values[LEADERBOARD_WIN_COUNT] = 1
+	}
+	if len(values) == 0 {
+		return
+	}
+
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+
+	now := pw.watermark.Now()
+	if eventTime.Before(now.Add(-WINDOW_RETENTION)) {
+		return
+	}
+
+	bucket := pw.bucket(event.PlayerID, toMinute(eventTime))
+	for metric, value := range values {
+		bucket.values[metric] += value
+	}
+	pw.expire(event.PlayerID, now)
+}
+
+// Top returns the first limit players of the metric summed over the window, ordered like the Leaderboard
+func (pw *PlayerWindows) Top(metric, window string, limit int) []LeaderboardEntry {
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+
+	now := pw.watermark.Now()
+	from, to := toMinute(windowStart(window, now, pw.location)), toMinute(now)
+
+	entries := make([]LeaderboardEntry, 0, len(pw.buckets))
+	for playerID := range pw.buckets {
+		pw.expire(playerID, now)
+
+		var value int64
+		found := false
+		for _, bucket := range pw.buckets[playerID] {
+			if bucket.minute < from || bucket.minute > to {
+				continue
+			}
+			if bucketValue, ok := bucket.values[metric]; ok {
+				value += bucketValue
+				found = true
+			}
+		}
+		if found {
+			entries = append(entries, LeaderboardEntry{PlayerID: playerID, Value: value})
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Value != entries[j].Value {
+			return entries[i].Value > entries[j].Value
+		}
+		return entries[i].PlayerID < entries[j].PlayerID
+	})
+
+	if limit > len(entries) {
+		limit = len(entries)
+	}
+	entries = entries[:limit]
+	for i := range entries {
+		entries[i].Rank = i + 1
+	}
+	return entries
+}
+
+// Player returns the leaderboard metrics of the player summed over each window except WINDOW_ALL
+func (pw *PlayerWindows) Player(playerID int) map[string]map[string]int64 {
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+
+	now := pw.watermark.Now()
+	pw.expire(playerID, now)
+
+	windows := make(map[string]map[string]int64, len(Windows)-1)
+	for _, window := range Windows {
+		if window == WINDOW_ALL {
+			continue
+		}
+
+		from, to := toMinute(windowStart(window, now, pw.location)), toMinute(now)
+		values := make(map[string]int64, len(LeaderboardMetrics))
+		for _, metric := range LeaderboardMetrics {
+			values[metric] = 0
+		}
+		for _, bucket := range pw.buckets[playerID] {
+			if bucket.minute < from || bucket.minute > to {
+				continue
+			}
+			for metric, value := range bucket.values {
+				values[metric] += value
+			}
+		}
+		windows[window] = values
+	}
+	return windows
+}
+
+// LateEvents returns the number of events created before the watermark
+func (pw *PlayerWindows) LateEvents() int64 {
+	return pw.lateEvents.Load()
+}
+
+// Returns the start of the window ending now, the calendar windows start in the location
+func windowStart(window string, now time.Time, location *time.Location) time.Time {
+	switch window {
+	case WINDOW_5M:
+		return now.Add(-5 * time.Minute)
+	case WINDOW_1H:
+		return now.Add(-time.Hour)
+	case WINDOW_24H:
+		return now.Add(-24 * time.Hour)
+	case WINDOW_TODAY:
+		local := now.In(location)
+		return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, location)
+	}
+	return now.Add(-WINDOW_RETENTION)
+}
+
+// Returns the bucket of the minute, events may arrive out of order so the bucket is searched from the end
+func (pw *PlayerWindows) bucket(playerID int, minute int64) *windowBucket {
+	buckets := pw.buckets[playerID]
+
+	i := len(buckets)
+	for i > 0 && buckets[i-1].minute > minute {
+		i--
+	}
+	if i > 0 && buckets[i-1].minute == minute {
+		return buckets[i-1]
+	}
+
+	bucket := &windowBucket{
+		minute: minute,
+		values: make(map[string]int64),
+	}
+	buckets = append(buckets, nil)
+	copy(buckets[i+1:], buckets[i:])
+	buckets[i] = bucket
+	pw.buckets[playerID] = buckets
+	return bucket
+}
+
+// Drop the expired buckets of the player
+func (pw *PlayerWindows) expire(playerID int, now time.Time) {
+	oldest := toMinute(now.Add(-WINDOW_RETENTION))
+	buckets := pw.buckets[playerID]
+
+	i := 0
+	for i < len(buckets) && buckets[i].minute < oldest {
+		i++
+	}
+	if i == len(buckets) {
+		delete(pw.buckets, playerID)
+		return
+	}
+	pw.buckets[playerID] = buckets[i:]
+}
+
+func toMinute(t time.Time) int64 {
+	return t.Unix() / int64(WINDOW_BUCKET/time.Second)
+}
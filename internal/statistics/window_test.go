@@ -0,0 +1,94 @@
+package statistics
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPlayerWindowsEndAtWatermark(t *testing.T) {
+	t.Setenv("ALLOWED_LATENESS", "2m")
+	pw := NewPlayerWindows(time.UTC, NewWatermark())
+
+	start := time.Now().Truncate(time.Minute).Add(-10 * time.Minute)
+	first := bet(1, 10, 100, false)
+	first.CreatedAt = start
+	pw.Record(first)
+	// The watermark is one minute after the first bet, the second bet is after it
+	second := bet(2, 20, 100, false)
+	second.CreatedAt = start.Add(3 * time.Minute)
+	pw.Record(second)
+
+	if top := pw.Top(LEADERBOARD_BET_COUNT, WINDOW_1H, 10); len(top) != 1 || top[0].PlayerID != 10 {
+		t.Fatalf("top before the watermark passed the second bet = %+v, want player 10", top)
+	}
+	if value := pw.Player(20)[WINDOW_1H][LEADERBOARD_BET_COUNT]; value != 0 {
+		t.Fatalf("player 20 bets before the watermark passed the bet = %d, want 0", value)
+	}
+
+	third := bet(3, 10, 100, false)
+	third.CreatedAt = start.Add(6 * time.Minute)
+	pw.Record(third)
+
+	if top := pw.Top(LEADERBOARD_BET_COUNT, WINDOW_1H, 10); len(top) != 2 {
+		t.Fatalf("top after the watermark passed the second bet = %+v, want players 10 and 20", top)
+	}
+	if value := pw.Player(20)[WINDOW_1H][LEADERBOARD_BET_COUNT]; value != 1 {
+		t.Fatalf("player 20 bets after the watermark passed the bet = %d, want 1", value)
+	}
+}
+
+func TestPlayerWindowsCountLateEventsInTheWindowsCoveringThem(t *testing.T) {
+	t.Setenv("ALLOWED_LATENESS", "0s")
+	pw := NewPlayerWindows(time.UTC, NewWatermark())
+
+	now := time.Now()
+	current := bet(1, 10, 100, false)
+	current.CreatedAt = now
+	pw.Record(current)
+
+	late := bet(2, 20, 300, false)
+	late.CreatedAt = now.Add(-2 * time.Hour)
+	pw.Record(late)
+
+	if pw.LateEvents() != 1 {
+		t.Fatalf("late events = %d, want 1", pw.LateEvents())
+	}
+	windows := pw.Player(20)
+	for window, want := range map[string]int64{WINDOW_5M: 0, WINDOW_1H: 0, WINDOW_24H: 300} {
+		if got := windows[window][LEADERBOARD_BET_AMOUNT]; got != want {
+			t.Errorf("%s bet amount of the late event = %d, want %d", window, got, want)
+		}
+	}
+	if top := pw.Top(LEADERBOARD_BET_AMOUNT, WINDOW_24H, 10); len(top) != 2 || top[0].PlayerID != 20 {
+		t.Fatalf("24h top = %+v, want the late player first", top)
+	}
+
+	expired := bet(3, 30, 100, false)
+	expired.CreatedAt = now.Add(-WINDOW_RETENTION - time.Hour)
+	pw.Record(expired)
+	if top := pw.Top(LEADERBOARD_BET_AMOUNT, WINDOW_24H, 10); len(top) != 2 {
+		t.Fatalf("24h top = %+v, want the expired event ignored", top)
+	}
+}
+
+func TestPlayerWindowsRollingWindows(t *testing.T) {
+	t.Setenv("ALLOWED_LATENESS", "0s")
+	pw := NewPlayerWindows(time.UTC, NewWatermark())
+
+	now := time.Now()
+	for i, age := range []time.Duration{23 * time.Hour, 30 * time.Minute, time.Minute, 0} {
+		event := bet(i+1, 10, 100, false)
+		event.CreatedAt = now.Add(-age)
+		pw.Record(event)
+	}
+
+	windows := pw.Player(10)
+	for window, want := range map[string]int64{WINDOW_5M: 2, WINDOW_1H: 3, WINDOW_24H: 4} {
+		if got := windows[window][LEADERBOARD_BET_COUNT]; got != want {
+			t.Errorf("%s bets = %d, want %d", window, got, want)
+		}
+	}
+	if top := pw.Top(LEADERBOARD_BET_AMOUNT, WINDOW_1H, 10); len(top) != 1 || top[0].Value != 300 || top[0].Rank != 1 {
+		t.Fatalf("1h bet amount leaderboard = %+v, want player 10 with 300", top)
+	}
+}
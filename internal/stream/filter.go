@@ -0,0 +1,27 @@
+package stream
+
+import "github.com/Bitstarz-eng/event-processing-challenge/internal/casino"
+
+// Filter selects the events of a client, zero-value fields are ignored
+type Filter struct {
+	Types        map[string]bool
+	PlayerID     int
+	GameID       int
+	MinAmountEUR int // EUR cents
+}
+
+func (f Filter) Matches(event *casino.Event) bool {
+	if len(f.Types) > 0 && !f.Types[event.Type] {
+		return false
+	}
+	if f.PlayerID != 0 && event.PlayerID != f.PlayerID {
+		return false
+	}
+	if f.GameID != 0 && event.GameID != f.GameID {
+		return false
+	}
+	if f.MinAmountEUR > 0 && event.AmountEUR < f.MinAmountEUR {
+		return false
+	}
+	return true
+}
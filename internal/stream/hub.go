@@ -0,0 +1,140 @@
+package stream
+
+import (
+	"sync"
+
+	"github.com/Bitstarz-eng/event-processing-challenge/internal/casino"
+)
+
+// Events buffered for each client, a client which falls behind is disconnected and resumes with Last-Event-ID
+const CLIENT_BUFFER_SIZE = 256
+
+// Message is an event of the stream, the IDs are sequential in the order of the stream
+type Message struct {
+	ID    uint64
+	Event casino.Event
+}
+
+// Client receives the messages matching its filter until it is unsubscribed or falls behind
+type Client struct {
+	Messages chan Message
+	filter   Filter
+}
+
+// HubStats is the stream part of the subscriber statistics
+type HubStats struct {
+	Clients        int    `json:"clients"`
+	Buffered       int    `json:"buffered"`
+	LastID         uint64 `json:"last_id"`
+	DroppedClients int64  `json:"dropped_clients"`
+}
+
+// Hub fans the events out to the clients and keeps the latest events in a ring buffer for the resume
+type Hub struct {
+	mu      sync.Mutex
+	buffer  []Message // Ring of the latest messages, the oldest at head
+	head    int
+	size    int
+	lastID  uint64
+	clients map[*Client]struct{}
+	dropped int64
+}
+
+func NewHub(bufferSize int) *Hub {
+	return &Hub{
+		buffer:  make([]Message, bufferSize),
+		clients: make(map[*Client]struct{}),
+	}
+}
+
+// Publish adds the event to the buffer and sends it to the matching clients, the clients which fall behind are dropped
+func (h *Hub) Publish(event *casino.Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.lastID++
+	message := Message{ID: h.lastID, Event: *event}
+
+	if h.size < len(h.buffer) {
+		h.buffer[(h.head+h.size)%len(h.buffer)] = message
+		h.size++
+	} else {
+		h.buffer[h.head] = message
+		h.head = (h.head + 1) % len(h.buffer)
+	}
+
+	for client := range h.clients {
+		if !client.filter.Matches(event) {
+			continue
+		}
+		select {
+		case client.Messages <- message:
+		default:
+			h.remove(client)
+			h.dropped++
+		}
+	}
+}
+
+// Subscribe registers a client and returns the buffered messages after lastID which match the filter.
+// Missed is true if messages after lastID are no longer buffered or lastID is from an earlier run of the stream.
+// Without a lastID (0) no messages are returned.
+func (h *Hub) Subscribe(filter Filter, lastID uint64) (client *Client, backlog []Message, missed bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if lastID > 0 {
+		for i := 0; i < h.size; i++ {
+			message := h.buffer[(h.head+i)%len(h.buffer)]
+			if message.ID > lastID && filter.Matches(&message.Event) {
+				backlog = append(backlog, message)
+			}
+		}
+		oldest := h.lastID - uint64(h.size) + 1
+		missed = lastID > h.lastID || lastID+1 < oldest
+	}
+
+	client = &Client{
+		Messages: make(chan Message, CLIENT_BUFFER_SIZE),
+		filter:   filter,
+	}
+	h.clients[client] = struct{}{}
+	return client, backlog, missed
+}
+
+// Unsubscribe removes the client, its channel is closed
+func (h *Hub) Unsubscribe(client *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.remove(client)
+}
+
+// Close removes all clients
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for client := range h.clients {
+		h.remove(client)
+	}
+}
+
+func (h *Hub) Stats() HubStats {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return HubStats{
+		Clients:        len(h.clients),
+		Buffered:       h.size,
+		LastID:         h.lastID,
+		DroppedClients: h.dropped,
+	}
+}
+
+func (h *Hub) remove(client *Client) {
+	if _, ok := h.clients[client]; ok {
+		delete(h.clients, client)
+		close(client.Messages)
+	}
+}
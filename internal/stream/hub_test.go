@@ -0,0 +1,61 @@
+package stream
+
+import (
+	"testing"
+
+	"github.com/Bitstarz-eng/event-processing-challenge/internal/casino"
+)
+
+func publish(h *Hub, events int) {
+	for i := 1; i <= events; i++ {
+		h.Publish(&casino.Event{ID: i, PlayerID: i % 2, Type: casino.BET, AmountEUR: i * 100})
+	}
+}
+
+func TestHubResume(t *testing.T) {
+	h := NewHub(3)
+	publish(h, 5) // Messages 3 to 5 are buffered
+
+	_, backlog, missed := h.Subscribe(Filter{}, 3)
+	if missed || len(backlog) != 2 || backlog[0].ID != 4 || backlog[1].ID != 5 {
+		t.Fatalf("resume after 3 = %+v, missed %v, want 4 and 5", backlog, missed)
+	}
+
+	if _, backlog, missed := h.Subscribe(Filter{}, 1); !missed || len(backlog) != 3 {
+		t.Fatalf("resume after 1 = %d messages, missed %v, want 3 messages and missed", len(backlog), missed)
+	}
+
+	if _, backlog, _ := h.Subscribe(Filter{MinAmountEUR: 500}, 2); len(backlog) != 1 || backlog[0].ID != 5 {
+		t.Fatalf("filtered resume = %+v, want 5", backlog)
+	}
+
+	if _, backlog, missed := h.Subscribe(Filter{}, 0); missed || len(backlog) != 0 {
+		t.Fatalf("new client = %d messages, missed %v, want none", len(backlog), missed)
+	}
+}
+
+func TestHubDropsSlowClients(t *testing.T) {
+	h := NewHub(10)
+	slow, _, _ := h.Subscribe(Filter{}, 0)
+	filtered, _, _ := h.Subscribe(Filter{PlayerID: 3}, 0) // Never matches
+
+	publish(h, CLIENT_BUFFER_SIZE+1)
+
+	received := 0
+	for range slow.Messages {
+		received++
+	}
+	if received != CLIENT_BUFFER_SIZE {
+		t.Fatalf("slow client received %d messages before it was dropped, want %d", received, CLIENT_BUFFER_SIZE)
+	}
+
+	stats := h.Stats()
+	if stats.Clients != 1 || stats.DroppedClients != 1 || stats.Buffered != 10 || stats.LastID != CLIENT_BUFFER_SIZE+1 {
+		t.Fatalf("stats = %+v, want 1 client, 1 dropped, 10 buffered", stats)
+	}
+
+	h.Unsubscribe(filtered)
+	if _, ok := <-filtered.Messages; ok {
+		t.Fatal("unsubscribed client has messages")
+	}
+}
@@ -2,6 +2,7 @@ package subscriber
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 
 	"github.com/Bitstarz-eng/event-processing-challenge/internal/casino"
@@ -10,21 +11,25 @@ import (
 
 type GameSubscriber struct {
 	BaseSubscriber *BaseSubscriber
-	Statistics     map[int]*statistics.GameData
+	Statistics     *statistics.GameAggregator
 }
 
 func NewGameSubscriber(name string) Subscriber {
 	baseSubscriber := NewBaseSubscriber(name)
+	baseSubscriber.Workers = getWorkers("GAME_SUBSCRIBER_WORKERS")
+	baseSubscriber.ShardKey = ShardByGame
 	gs := &GameSubscriber{
 		BaseSubscriber: baseSubscriber,
-		Statistics:     make(map[int]*statistics.GameData),
+		Statistics:     statistics.NewGameAggregator(),
 	}
 
 	gs.BaseSubscriber.EventHandler = gs.HandleEvent
+	gs.BaseSubscriber.Snapshotter = gs
 	return gs
 }
 
 func (gs *GameSubscriber) Subscribe(ctx context.Context, channel, stopSignal string) {
+	gs.Statistics.Unique.Reset(ctx)
 	gs.BaseSubscriber.Subscribe(ctx, channel, stopSignal)
 }
 
@@ -33,32 +38,47 @@ func (gs *GameSubscriber) Unsubscribe(ctx context.Context, channel string) {
 }
 
 func (gs *GameSubscriber) HandleEvent(event *casino.Event) {
-	gameId := event.GameID
-	gd, ok := gs.Statistics[gameId]
-	if !ok {
-		gd = statistics.NewGameData(gameId)
-		gs.Statistics[gameId] = gd
-	}
+	gs.Statistics.HandleEvent(event)
+}
 
-	switch event.Type {
-	case casino.GAME_STOP:
-		gd.GamePlayedCounter++
-		statistics.CalculateMostPlayedGame(gameId, gd.GamePlayedCounter)
-	case casino.BET:
-		gd.BetPerCurrency[event.Currency] += float64(event.Amount) * casino.SmallestUnit[event.Currency]
-		statistics.CalculateMostBettedGame(gameId, event.AmountEUR)
-	default:
-		break
-	}
+// GetGames returns the summaries of all played games ordered by game ID
+func (gs *GameSubscriber) GetGames() []statistics.GameSummary {
+	return gs.Statistics.Games()
+}
+
+// GetGame returns the summary of the game, false if the game is unknown
+func (gs *GameSubscriber) GetGame(gameId int) (statistics.GameSummary, bool) {
+	return gs.Statistics.Game(gameId)
 }
 
 func (gs *GameSubscriber) GetStats() interface{} {
+	return gs.Statistics.Snapshot()
+}
+
+func (gs *GameSubscriber) SnapshotVersion() int {
+	return statistics.GAME_STATE_VERSION
+}
+
+func (gs *GameSubscriber) SaveState() interface{} {
+	return gs.Statistics.State()
+}
+
+func (gs *GameSubscriber) RestoreState(data json.RawMessage) error {
+	var state statistics.GameState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+	gs.Statistics.Restore(&state)
 	return nil
 }
 
+func (gs *GameSubscriber) GetRejected() map[string]int64 {
+	return gs.BaseSubscriber.Rejected.Snapshot()
+}
+
 func (gs *GameSubscriber) ShowStat() {
 	fmt.Println("Game Statistics:")
-	for _, gd := range gs.Statistics {
-		fmt.Printf("%v\n", gd)
+	for _, game := range gs.Statistics.Games() {
+		fmt.Printf("%v\n", game)
 	}
 }
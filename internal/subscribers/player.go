@@ -2,6 +2,7 @@ package subscriber
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 
 	"github.com/Bitstarz-eng/event-processing-challenge/internal/casino"
@@ -10,17 +11,20 @@ import (
 
 type PlayerSubscriber struct {
 	BaseSubscriber *BaseSubscriber
-	Statistics     map[int]*statistics.PlayerData
+	Statistics     *statistics.PlayerAggregator
 }
 
 func NewPlayerSubscriber(name string) Subscriber {
 	baseSubscriber := NewBaseSubscriber(name)
+	baseSubscriber.Workers = getWorkers("PLAYER_SUBSCRIBER_WORKERS")
+	baseSubscriber.ShardKey = ShardByPlayer
 	ps := &PlayerSubscriber{
 		BaseSubscriber: baseSubscriber,
-		Statistics:     make(map[int]*statistics.PlayerData),
+		Statistics:     statistics.NewPlayerAggregator(),
 	}
 
 	ps.BaseSubscriber.EventHandler = ps.HandleEvent
+	ps.BaseSubscriber.Snapshotter = ps
 	return ps
 }
 
@@ -33,35 +37,37 @@ func (ps *PlayerSubscriber) Unsubscribe(ctx context.Context, channel string) {
 }
 
 func (ps *PlayerSubscriber) HandleEvent(event *casino.Event) {
-	id := event.PlayerID
-	spd, ok := ps.Statistics[id]
-	if !ok {
-		spd = statistics.NewPlayerData()
-		ps.Statistics[id] = spd
-	}
+	ps.Statistics.HandleEvent(event)
+}
 
-	switch event.Type {
-	case casino.BET:
-		spd.CalculateBetValues(id, event.AmountEUR)
-	case casino.DEPOSIT:
-		spd.CalculateDepositValues(id, event.AmountEUR)
-	default:
-		break
-	}
+func (ps *PlayerSubscriber) GetStats() interface{} {
+	return ps.Statistics
+}
 
-	if event.HasWon {
-		spd.CalculateWonValues(id)
-	}
+func (ps *PlayerSubscriber) SnapshotVersion() int {
+	return statistics.PLAYER_STATE_VERSION
+}
 
+func (ps *PlayerSubscriber) SaveState() interface{} {
+	return ps.Statistics.State()
 }
 
-func (ps *PlayerSubscriber) GetStats() interface{} {
-	return statistics.GetStats()
+func (ps *PlayerSubscriber) RestoreState(data json.RawMessage) error {
+	var state statistics.PlayerState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+	ps.Statistics.Restore(&state)
+	return nil
+}
+
+func (ps *PlayerSubscriber) GetRejected() map[string]int64 {
+	return ps.BaseSubscriber.Rejected.Snapshot()
 }
 
 func (ps *PlayerSubscriber) ShowStat() {
 	fmt.Println("Player Statistics:")
-	for id, pd := range ps.Statistics {
+	for id, pd := range ps.Statistics.Players() {
 		fmt.Printf("Player %d: %v\n", id, pd)
 	}
 }
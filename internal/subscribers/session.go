@@ -0,0 +1,82 @@
+package subscriber
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Bitstarz-eng/event-processing-challenge/internal/casino"
+	"github.com/Bitstarz-eng/event-processing-challenge/internal/config"
+	"github.com/Bitstarz-eng/event-processing-challenge/internal/statistics"
+)
+
+// Interval of closing the inactive sessions while no events arrive
+const SESSION_EXPIRE_INTERVAL = 10 * time.Second
+
+// SessionSubscriber tracks the game sessions of the players
+type SessionSubscriber struct {
+	BaseSubscriber *BaseSubscriber
+	Statistics     *statistics.SessionTracker
+}
+
+func NewSessionSubscriber(name string) Subscriber {
+	timeout := config.GetDuration("SESSION_TIMEOUT", statistics.DEFAULT_SESSION_TIMEOUT)
+	if timeout <= 0 {
+		log.Fatalf("SESSION_TIMEOUT must be positive, got %v", timeout)
+	}
+
+	baseSubscriber := NewBaseSubscriber(name)
+	baseSubscriber.Workers = getWorkers("SESSION_SUBSCRIBER_WORKERS")
+	baseSubscriber.ShardKey = ShardByPlayer
+	ss := &SessionSubscriber{
+		BaseSubscriber: baseSubscriber,
+		Statistics:     statistics.NewSessionTracker(timeout),
+	}
+
+	ss.BaseSubscriber.EventHandler = ss.HandleEvent
+	return ss
+}
+
+func (ss *SessionSubscriber) Subscribe(ctx context.Context, channel, stopSignal string) {
+	done := make(chan struct{})
+	go ss.expirePeriodically(done)
+
+	ss.BaseSubscriber.Subscribe(ctx, channel, stopSignal)
+	close(done)
+}
+
+func (ss *SessionSubscriber) Unsubscribe(ctx context.Context, channel string) {
+	ss.BaseSubscriber.Unsubscribe(ctx, channel)
+}
+
+func (ss *SessionSubscriber) HandleEvent(event *casino.Event) {
+	ss.Statistics.HandleEvent(event)
+}
+
+// Close the inactive sessions on every interval, so the active sessions are up to date without events
+func (ss *SessionSubscriber) expirePeriodically(done <-chan struct{}) {
+	ticker := time.NewTicker(SESSION_EXPIRE_INTERVAL)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			ss.Statistics.Expire()
+		case <-done:
+			return
+		}
+	}
+}
+
+func (ss *SessionSubscriber) GetStats() interface{} {
+	return ss.Statistics.Stats()
+}
+
+func (ss *SessionSubscriber) GetRejected() map[string]int64 {
+	return ss.BaseSubscriber.Rejected.Snapshot()
+}
+
+func (ss *SessionSubscriber) ShowStat() {
+	fmt.Printf("Session Statistics:\n%v\n", ss.Statistics.Stats())
+}
@@ -0,0 +1,85 @@
+package subscriber
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"time"
+
+	"github.com/Bitstarz-eng/event-processing-challenge/internal/config"
+	"github.com/Bitstarz-eng/event-processing-challenge/internal/snapshot"
+)
+
+const DEFAULT_SNAPSHOT_INTERVAL = 30 * time.Second
+
+// Snapshotter is implemented by the subscribers which keep their statistics over restarts
+type Snapshotter interface {
+	SnapshotVersion() int
+	SaveState() interface{}
+	RestoreState(state json.RawMessage) error
+}
+
+// Snapshots are opt-in: enabled by SNAPSHOT_DIR, saved every SNAPSHOT_INTERVAL and on shutdown
+func getSnapshotConfig() (string, time.Duration) {
+	dir := config.GetString("SNAPSHOT_DIR", "")
+	interval := config.GetDuration("SNAPSHOT_INTERVAL", DEFAULT_SNAPSHOT_INTERVAL)
+	if interval <= 0 {
+		log.Fatalf("SNAPSHOT_INTERVAL must be positive, got %v", interval)
+	}
+	return dir, interval
+}
+
+func (bs *BaseSubscriber) snapshotsEnabled() bool {
+	return bs.Snapshotter != nil && bs.SnapshotDir != ""
+}
+
+// Restore the statistics of the previous run, a missing or incompatible snapshot starts from zero
+func (bs *BaseSubscriber) restoreSnapshot() {
+	if err := os.MkdirAll(bs.SnapshotDir, 0o755); err != nil {
+		log.Fatalf("%s: Failed to create snapshot directory: %v", bs.Name, err)
+	}
+
+	path := snapshot.Path(bs.SnapshotDir, bs.Name)
+	state, err := snapshot.Load(path, bs.Snapshotter.SnapshotVersion())
+	if errors.Is(err, os.ErrNotExist) {
+		log.Printf("%s: No snapshot in %s", bs.Name, path)
+		return
+	}
+	if err != nil {
+		log.Printf("%s: Rejected snapshot %s: %v", bs.Name, path, err)
+		return
+	}
+
+	if err := bs.Snapshotter.RestoreState(state); err != nil {
+		log.Printf("%s: Failed to restore snapshot %s: %v", bs.Name, path, err)
+		return
+	}
+	log.Printf("%s: Restored snapshot %s", bs.Name, path)
+}
+
+// Save the statistics, the workers are paused while the state is copied so it doesn't mix the statistics before and after an event
+func (bs *BaseSubscriber) saveSnapshot() {
+	bs.handling.Lock()
+	state := bs.Snapshotter.SaveState()
+	bs.handling.Unlock()
+
+	path := snapshot.Path(bs.SnapshotDir, bs.Name)
+	if err := snapshot.Save(path, bs.Name, bs.Snapshotter.SnapshotVersion(), state); err != nil {
+		log.Printf("%s: Failed to save snapshot %s: %v", bs.Name, path, err)
+	}
+}
+
+func (bs *BaseSubscriber) snapshotPeriodically(done <-chan struct{}) {
+	ticker := time.NewTicker(bs.SnapshotInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			bs.saveSnapshot()
+		case <-done:
+			return
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package subscriber
+
+import (
+	"encoding/json"
+	"reflect"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Bitstarz-eng/event-processing-challenge/internal/casino"
+	"github.com/Bitstarz-eng/event-processing-challenge/internal/statistics"
+)
+
+// halfwayStats counts every event twice, a state saved in the middle of an event has different counts
+type halfwayStats struct {
+	first, second atomic.Int64
+	inconsistent  atomic.Int64
+}
+
+func (hs *halfwayStats) HandleEvent(event *casino.Event) {
+	hs.first.Add(1)
+	runtime.Gosched()
+	hs.second.Add(1)
+}
+
+func (hs *halfwayStats) SnapshotVersion() int { return 1 }
+
+func (hs *halfwayStats) SaveState() interface{} {
+	first, second := hs.first.Load(), hs.second.Load()
+	if first != second {
+		hs.inconsistent.Add(1)
+	}
+	return []int64{first, second}
+}
+
+func (hs *halfwayStats) RestoreState(data json.RawMessage) error { return nil }
+
+func TestSnapshotPausesTheWorkers(t *testing.T) {
+	stats := &halfwayStats{}
+	bs := &BaseSubscriber{Name: "HalfwaySubscriber", Workers: 4, ShardKey: ShardByEvent, EventHandler: stats.HandleEvent, Snapshotter: stats, SnapshotDir: t.TempDir()}
+
+	queues, workers := bs.startWorkers()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for id := 1; id <= 20000; id++ {
+			event := &casino.Event{ID: id}
+			bs.queue(queues, event) <- event
+		}
+	}()
+
+	for snapshots := 0; snapshots < 100; snapshots++ {
+		bs.saveSnapshot()
+	}
+	<-done
+	stopWorkers(queues, workers)
+
+	if inconsistent := stats.inconsistent.Load(); inconsistent > 0 {
+		t.Fatalf("%d snapshots saved in the middle of an event", inconsistent)
+	}
+}
+
+func newTestPlayerSubscriber(snapshotDir string, workers int) *PlayerSubscriber {
+	ps := &PlayerSubscriber{
+		BaseSubscriber: &BaseSubscriber{
+			Name:        PLAYER_SUB,
+			Workers:     workers,
+			ShardKey:    ShardByPlayer,
+			Rejected:    statistics.NewValidationStats(),
+			SnapshotDir: snapshotDir,
+		},
+		Statistics: statistics.NewPlayerAggregator(),
+	}
+	ps.BaseSubscriber.EventHandler = ps.HandleEvent
+	ps.BaseSubscriber.Snapshotter = ps
+	return ps
+}
+
+// Returns the bets of all players and the bets summed over their 24h windows
+func countBets(pa *statistics.PlayerAggregator) (int64, int64) {
+	var bets, windowBets int64
+	for id, player := range pa.Players() {
+		bets += player.BetCount
+		windowBets += pa.Windows.Player(id)[statistics.WINDOW_24H][statistics.LEADERBOARD_BET_COUNT]
+	}
+	return bets, windowBets
+}
+
+func TestSnapshotRoundTripWithConcurrentEvents(t *testing.T) {
+	t.Setenv("ALLOWED_LATENESS", "0s")
+	const events = 20000
+	dir := t.TempDir()
+	ps := newTestPlayerSubscriber(dir, 4)
+
+	queues, workers := ps.BaseSubscriber.startWorkers()
+	createdAt := time.Now()
+	var feeding sync.WaitGroup
+	feeding.Add(1)
+	go func() {
+		defer feeding.Done()
+		for id := 1; id <= events; id++ {
+			event := &casino.Event{ID: id, PlayerID: 1 + id%200, GameID: 100, Type: casino.BET, Amount: 100, Currency: "EUR", AmountEUR: 100, CreatedAt: createdAt}
+			ps.BaseSubscriber.queue(queues, event) <- event
+		}
+	}()
+
+	// Each snapshot taken while the events are handled restores to consistent statistics
+	for i := 0; i < 20; i++ {
+		ps.BaseSubscriber.saveSnapshot()
+
+		restored := newTestPlayerSubscriber(dir, 1)
+		restored.BaseSubscriber.restoreSnapshot()
+		if bets, windowBets := countBets(restored.Statistics); bets != windowBets {
+			t.Fatalf("snapshot %d has %d bets of the players but %d in their windows", i, bets, windowBets)
+		}
+	}
+
+	feeding.Wait()
+	stopWorkers(queues, workers)
+
+	ps.BaseSubscriber.saveSnapshot()
+	restored := newTestPlayerSubscriber(dir, 1)
+	restored.BaseSubscriber.restoreSnapshot()
+	if got, want := restored.Statistics.Players(), ps.Statistics.Players(); !reflect.DeepEqual(got, want) {
+		t.Fatal("restored players differ from the saved players")
+	}
+	if bets, _ := countBets(restored.Statistics); bets != events {
+		t.Fatalf("restored %d bets, want %d", bets, events)
+	}
+}
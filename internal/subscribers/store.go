@@ -0,0 +1,169 @@
+package subscriber
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/Bitstarz-eng/event-processing-challenge/internal/casino"
+	"github.com/Bitstarz-eng/event-processing-challenge/internal/config"
+	"github.com/Bitstarz-eng/event-processing-challenge/internal/db"
+	"github.com/Bitstarz-eng/event-processing-challenge/internal/statistics"
+)
+
+const (
+	DEFAULT_STORE_BATCH_SIZE     = 100
+	DEFAULT_STORE_FLUSH_INTERVAL = 1 * time.Second
+
+	// Events kept while the event store is unavailable, newer events are dropped
+	DEFAULT_STORE_MAX_PENDING = 100000
+
+	// Postgres allows at most 65535 parameters per statement, 12 are used per event
+	MAX_STORE_BATCH_SIZE = 5000
+)
+
+// EventStore inserts the events and returns the number of inserted (not yet stored) events, implemented by db.DB
+type EventStore interface {
+	InsertEvents(events []casino.Event) (int64, error)
+}
+
+// EventStoreSubscriber appends every enriched event to the Postgres event store
+type EventStoreSubscriber struct {
+	BaseSubscriber *BaseSubscriber
+	DB             EventStore
+	Statistics     *statistics.StoreStats
+
+	mu            sync.Mutex
+	pending       []casino.Event
+	batchSize     int
+	maxPending    int
+	flushInterval time.Duration
+}
+
+func NewEventStoreSubscriber(name string) Subscriber {
+	batchSize := config.GetInt("EVENT_STORE_BATCH_SIZE", DEFAULT_STORE_BATCH_SIZE)
+	if batchSize < 1 || batchSize > MAX_STORE_BATCH_SIZE {
+		log.Fatalf("EVENT_STORE_BATCH_SIZE must be between 1 and %d", MAX_STORE_BATCH_SIZE)
+	}
+	maxPending := config.GetInt("EVENT_STORE_MAX_PENDING", DEFAULT_STORE_MAX_PENDING)
+	if maxPending < batchSize {
+		log.Fatalf("EVENT_STORE_MAX_PENDING must be at least EVENT_STORE_BATCH_SIZE (%d), got %d", batchSize, maxPending)
+	}
+	flushInterval := config.GetDuration("EVENT_STORE_FLUSH_INTERVAL", DEFAULT_STORE_FLUSH_INTERVAL)
+	if flushInterval <= 0 {
+		log.Fatalf("EVENT_STORE_FLUSH_INTERVAL must be positive, got %v", flushInterval)
+	}
+
+	baseSubscriber := NewBaseSubscriber(name)
+	baseSubscriber.Workers = getWorkers("EVENT_STORE_WORKERS")
+	baseSubscriber.ShardKey = ShardByEvent
+	es := &EventStoreSubscriber{
+		BaseSubscriber: baseSubscriber,
+		DB:             db.GetDB(),
+		Statistics:     statistics.NewStoreStats(),
+		pending:        make([]casino.Event, 0, batchSize),
+		batchSize:      batchSize,
+		maxPending:     maxPending,
+		flushInterval:  flushInterval,
+	}
+
+	es.BaseSubscriber.EventHandler = es.HandleEvent
+	return es
+}
+
+func (es *EventStoreSubscriber) Subscribe(ctx context.Context, channel, stopSignal string) {
+	done := make(chan struct{})
+	go es.flushPeriodically(done)
+
+	es.BaseSubscriber.Subscribe(ctx, channel, stopSignal)
+
+	// Store the pending events left
+	close(done)
+	es.flush()
+}
+
+func (es *EventStoreSubscriber) Unsubscribe(ctx context.Context, channel string) {
+	es.BaseSubscriber.Unsubscribe(ctx, channel)
+}
+
+func (es *EventStoreSubscriber) HandleEvent(event *casino.Event) {
+	es.mu.Lock()
+	if len(es.pending) >= es.maxPending {
+		es.mu.Unlock()
+		es.Statistics.Dropped.Add(1)
+		return
+	}
+	es.pending = append(es.pending, *event)
+	full := len(es.pending) >= es.batchSize
+	es.mu.Unlock()
+
+	if full {
+		es.flush()
+	}
+}
+
+// Flush the pending events on every interval so slow traffic is stored as well
+func (es *EventStoreSubscriber) flushPeriodically(done <-chan struct{}) {
+	ticker := time.NewTicker(es.flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			es.flush()
+		case <-done:
+			return
+		}
+	}
+}
+
+// Insert the pending events into the event store in batches of the batch size.
+// If an insert fails, the events are kept and retried with the next flush.
+// At most maxPending events are kept, the newest events are dropped.
+func (es *EventStoreSubscriber) flush() {
+	for {
+		es.mu.Lock()
+		size := len(es.pending)
+		if size == 0 {
+			es.mu.Unlock()
+			return
+		}
+		if size > es.batchSize {
+			size = es.batchSize
+		}
+		events := make([]casino.Event, size)
+		copy(events, es.pending)
+		es.pending = es.pending[size:]
+		es.mu.Unlock()
+
+		inserted, err := es.DB.InsertEvents(events)
+		if err != nil {
+			log.Printf("%s: Failed to store %d events: %v", es.BaseSubscriber.Name, len(events), err)
+			es.Statistics.Failed.Add(1)
+
+			es.mu.Lock()
+			es.pending = append(events, es.pending...)
+			if excess := len(es.pending) - es.maxPending; excess > 0 {
+				es.pending = es.pending[:es.maxPending]
+				es.Statistics.Dropped.Add(int64(excess))
+			}
+			es.mu.Unlock()
+			return
+		}
+		es.Statistics.AddBatch(len(events), inserted)
+	}
+}
+
+func (es *EventStoreSubscriber) GetStats() interface{} {
+	return es.Statistics
+}
+
+func (es *EventStoreSubscriber) GetRejected() map[string]int64 {
+	return es.BaseSubscriber.Rejected.Snapshot()
+}
+
+func (es *EventStoreSubscriber) ShowStat() {
+	fmt.Printf("Event Store Statistics:\n%v\n", es.Statistics)
+}
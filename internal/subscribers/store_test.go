@@ -0,0 +1,115 @@
+package subscriber
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/Bitstarz-eng/event-processing-challenge/internal/casino"
+	"github.com/Bitstarz-eng/event-processing-challenge/internal/statistics"
+)
+
+// fakeEventStore keeps the inserted events and skips the already stored run and event IDs, like the events table
+type fakeEventStore struct {
+	fail    bool
+	batches []int
+	events  []casino.Event
+	stored  map[string]bool
+}
+
+func (fs *fakeEventStore) InsertEvents(events []casino.Event) (int64, error) {
+	if fs.fail {
+		return 0, errors.New("database is down")
+	}
+	if fs.stored == nil {
+		fs.stored = make(map[string]bool)
+	}
+
+	fs.batches = append(fs.batches, len(events))
+	var inserted int64
+	for _, event := range events {
+		key := fmt.Sprintf("%s/%d", event.RunID, event.ID)
+		if fs.stored[key] {
+			continue
+		}
+		fs.stored[key] = true
+		fs.events = append(fs.events, event)
+		inserted++
+	}
+	return inserted, nil
+}
+
+func newTestStoreSubscriber(store EventStore, batchSize, maxPending int) *EventStoreSubscriber {
+	return &EventStoreSubscriber{
+		BaseSubscriber: &BaseSubscriber{Name: STORE_SUB},
+		DB:             store,
+		Statistics:     statistics.NewStoreStats(),
+		batchSize:      batchSize,
+		maxPending:     maxPending,
+	}
+}
+
+func storedEvent(runID string, id int) *casino.Event {
+	return &casino.Event{ID: id, RunID: runID, PlayerID: 10, Type: casino.DEPOSIT}
+}
+
+func TestEventStoreBatches(t *testing.T) {
+	store := &fakeEventStore{}
+	es := newTestStoreSubscriber(store, 3, 100)
+
+	for id := 1; id <= 7; id++ {
+		es.HandleEvent(storedEvent("run", id))
+	}
+	if len(store.batches) != 2 || store.batches[0] != 3 || store.batches[1] != 3 {
+		t.Fatalf("inserted batches = %v, want 3 and 3", store.batches)
+	}
+
+	es.flush()
+	if len(store.batches) != 3 || store.batches[2] != 1 || es.Statistics.Stored.Load() != 7 {
+		t.Fatalf("inserted batches = %v, stored %d, want the last event flushed and 7 stored", store.batches, es.Statistics.Stored.Load())
+	}
+}
+
+func TestEventStoreSkipsDuplicates(t *testing.T) {
+	store := &fakeEventStore{}
+	es := newTestStoreSubscriber(store, 10, 100)
+
+	es.HandleEvent(storedEvent("first", 1))
+	es.HandleEvent(storedEvent("first", 2))
+	es.HandleEvent(storedEvent("first", 1)) // Redelivered
+	es.HandleEvent(storedEvent("second", 1))
+	es.flush()
+
+	if stored, duplicates := es.Statistics.Stored.Load(), es.Statistics.Duplicates.Load(); stored != 3 || duplicates != 1 {
+		t.Fatalf("stored/duplicates = %d/%d, want 3/1", stored, duplicates)
+	}
+}
+
+func TestEventStoreRetriesAndDropsPendingEvents(t *testing.T) {
+	store := &fakeEventStore{fail: true}
+	es := newTestStoreSubscriber(store, 2, 5)
+
+	for id := 1; id <= 8; id++ {
+		es.HandleEvent(storedEvent("run", id))
+	}
+	if len(es.pending) != 5 || es.Statistics.Dropped.Load() != 3 || es.Statistics.Failed.Load() == 0 {
+		t.Fatalf("pending/dropped/failed = %d/%d/%d, want 5/3/some", len(es.pending), es.Statistics.Dropped.Load(), es.Statistics.Failed.Load())
+	}
+
+	store.fail = false
+	es.flush()
+
+	if len(es.pending) != 0 || len(store.events) != 5 {
+		t.Fatalf("pending/stored after recovery = %d/%d, want 0/5", len(es.pending), len(store.events))
+	}
+	for i, event := range store.events {
+		if event.ID != i+1 {
+			t.Fatalf("stored event %d has ID %d, want the oldest events in order", i, event.ID)
+		}
+	}
+	for _, size := range store.batches {
+		if size > 2 {
+			t.Fatalf("inserted batches = %v, want at most 2 events per insert", store.batches)
+		}
+	}
+}
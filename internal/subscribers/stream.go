@@ -0,0 +1,61 @@
+package subscriber
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/Bitstarz-eng/event-processing-challenge/internal/casino"
+	"github.com/Bitstarz-eng/event-processing-challenge/internal/config"
+	"github.com/Bitstarz-eng/event-processing-challenge/internal/stream"
+)
+
+// Default number of the latest events kept for the Last-Event-ID resume, configured by STREAM_BUFFER_SIZE
+const DEFAULT_STREAM_BUFFER_SIZE = 1000
+
+// StreamSubscriber fans the enriched events out to the /events/stream clients
+type StreamSubscriber struct {
+	BaseSubscriber *BaseSubscriber
+	Hub            *stream.Hub
+}
+
+func NewStreamSubscriber(name string) Subscriber {
+	bufferSize := config.GetInt("STREAM_BUFFER_SIZE", DEFAULT_STREAM_BUFFER_SIZE)
+	if bufferSize <= 0 {
+		log.Fatalf("STREAM_BUFFER_SIZE must be positive, got %d", bufferSize)
+	}
+
+	// One worker keeps the stream in the order of the channel
+	baseSubscriber := NewBaseSubscriber(name)
+	ss := &StreamSubscriber{
+		BaseSubscriber: baseSubscriber,
+		Hub:            stream.NewHub(bufferSize),
+	}
+
+	ss.BaseSubscriber.EventHandler = ss.HandleEvent
+	return ss
+}
+
+func (ss *StreamSubscriber) Subscribe(ctx context.Context, channel, stopSignal string) {
+	ss.BaseSubscriber.Subscribe(ctx, channel, stopSignal)
+}
+
+func (ss *StreamSubscriber) Unsubscribe(ctx context.Context, channel string) {
+	ss.BaseSubscriber.Unsubscribe(ctx, channel)
+}
+
+func (ss *StreamSubscriber) HandleEvent(event *casino.Event) {
+	ss.Hub.Publish(event)
+}
+
+func (ss *StreamSubscriber) GetStats() interface{} {
+	return ss.Hub.Stats()
+}
+
+func (ss *StreamSubscriber) GetRejected() map[string]int64 {
+	return ss.BaseSubscriber.Rejected.Snapshot()
+}
+
+func (ss *StreamSubscriber) ShowStat() {
+	fmt.Printf("Stream Statistics:\n%+v\n", ss.Hub.Stats())
+}
@@ -4,9 +4,13 @@ import (
 	"context"
 	"encoding/json"
 	"log"
+	"sync"
+	"time"
 
 	"github.com/Bitstarz-eng/event-processing-challenge/internal/casino"
+	"github.com/Bitstarz-eng/event-processing-challenge/internal/config"
 	rds "github.com/Bitstarz-eng/event-processing-challenge/internal/redis"
+	"github.com/Bitstarz-eng/event-processing-challenge/internal/statistics"
 	"github.com/go-redis/redis/v8"
 )
 
@@ -15,30 +19,121 @@ type Subscriber interface {
 	Unsubscribe(ctx context.Context, channel string)
 	HandleEvent(*casino.Event)
 	GetStats() interface{}
+	GetRejected() map[string]int64
 	ShowStat() // Test purpose
 }
 
+// Size of the queue of each handler worker
+const WORKER_QUEUE_SIZE = 1024
+
 type BaseSubscriber struct {
 	Name         string
 	RedisClient  *redis.Client
 	PubSub       *redis.PubSub
 	EventHandler func(*casino.Event)
+
+	// Number of handler workers, events with the same shard key are handled by the same worker in order
+	Workers  int
+	ShardKey func(*casino.Event) int
+
+	// Invalid events per validation rule, they are not handled
+	Rejected *statistics.ValidationStats
+
+	// Statistics saved in SnapshotDir and restored on start, nil if the subscriber has no snapshots
+	Snapshotter      Snapshotter
+	SnapshotDir      string
+	SnapshotInterval time.Duration
+
+	// Held for reading by the workers while they handle an event, the snapshots hold it to pause the workers
+	handling sync.RWMutex
 }
 
 func NewBaseSubscriber(name string) *BaseSubscriber {
+	snapshotDir, snapshotInterval := getSnapshotConfig()
 	return &BaseSubscriber{
-		Name:        name,
-		RedisClient: rds.GetRedisClient(),
+		Name:             name,
+		RedisClient:      rds.GetRedisClient(),
+		Workers:          1,
+		ShardKey:         ShardByEvent,
+		Rejected:         statistics.NewValidationStats(),
+		SnapshotDir:      snapshotDir,
+		SnapshotInterval: snapshotInterval,
 	}
 }
 
+// Shard keys of the handler workers
+func ShardByPlayer(event *casino.Event) int { return event.PlayerID }
+func ShardByGame(event *casino.Event) int   { return event.GameID }
+func ShardByEvent(event *casino.Event) int  { return event.ID }
+
+// Returns the number of handler workers configured by the environment variable, 1 by default
+func getWorkers(key string) int {
+	workers := config.GetInt(key, 1)
+	if workers < 1 {
+		log.Fatalf("%s must be positive, got %d", key, workers)
+	}
+	return workers
+}
+
+// Start the handler workers, each worker handles the events of its queue in order
+func (bs *BaseSubscriber) startWorkers() ([]chan *casino.Event, *sync.WaitGroup) {
+	queues := make([]chan *casino.Event, bs.Workers)
+	wg := &sync.WaitGroup{}
+	for i := range queues {
+		queues[i] = make(chan *casino.Event, WORKER_QUEUE_SIZE)
+		wg.Add(1)
+		go func(queue <-chan *casino.Event) {
+			defer wg.Done()
+			for event := range queue {
+				bs.handling.RLock()
+				bs.EventHandler(event)
+				bs.handling.RUnlock()
+			}
+		}(queues[i])
+	}
+	return queues, wg
+}
+
+// Close the queues and wait until the workers handled the queued events
+func stopWorkers(queues []chan *casino.Event, workers *sync.WaitGroup) {
+	for _, queue := range queues {
+		close(queue)
+	}
+	workers.Wait()
+}
+
+// Returns the queue of the event shard
+func (bs *BaseSubscriber) queue(queues []chan *casino.Event, event *casino.Event) chan *casino.Event {
+	shard := bs.ShardKey(event) % len(queues)
+	if shard < 0 {
+		shard = -shard
+	}
+	return queues[shard]
+}
+
 func (bs *BaseSubscriber) Subscribe(ctx context.Context, channel, stopSignal string) {
+	// The last snapshot is saved after all events are handled
+	if bs.snapshotsEnabled() {
+		bs.restoreSnapshot()
+
+		done := make(chan struct{})
+		go bs.snapshotPeriodically(done)
+		defer func() {
+			close(done)
+			bs.saveSnapshot()
+		}()
+	}
+
 	log.Printf("%s subscribed to %s\n", bs.Name, channel)
 	bs.PubSub = bs.RedisClient.Subscribe(ctx, channel)
 	defer bs.PubSub.Close()
 
 	ch := bs.PubSub.Channel()
 
+	// The queued events are handled before returning
+	queues, workers := bs.startWorkers()
+	defer stopWorkers(queues, workers)
+
 	for {
 		select {
 		case msg, ok := <-ch:
@@ -61,8 +156,15 @@ func (bs *BaseSubscriber) Subscribe(ctx context.Context, channel, stopSignal str
 				log.Printf("%s: Failed to unmarshal event: %v", bs.Name, err)
 				continue
 			}
-			// Handle the event
-			bs.EventHandler(&event)
+
+			// Reject invalid events
+			if err := event.Validate(); err != nil {
+				bs.reject(&event, err)
+				continue
+			}
+
+			// Handle the event by the worker of its shard
+			bs.queue(queues, &event) <- &event
 
 		case <-ctx.Done():
 			// Context is canceled, exit the loop
@@ -72,6 +174,11 @@ func (bs *BaseSubscriber) Subscribe(ctx context.Context, channel, stopSignal str
 	}
 }
 
+func (bs *BaseSubscriber) reject(event *casino.Event, err error) {
+	bs.Rejected.Add(casino.ValidationRule(err))
+	log.Printf("%s: Rejected event %d: %v", bs.Name, event.ID, err)
+}
+
 func (bs *BaseSubscriber) Unsubscribe(ctx context.Context, channel string) {
 	err := bs.PubSub.Unsubscribe(ctx, channel)
 	if err != nil {
@@ -81,15 +188,21 @@ func (bs *BaseSubscriber) Unsubscribe(ctx context.Context, channel string) {
 }
 
 const (
-	PLAYER_SUB = "PlayerSubscriber"
-	GAME_SUB   = "GameSubscriber"
-	TIME_SUB   = "TimeSubscriber"
+	PLAYER_SUB  = "PlayerSubscriber"
+	GAME_SUB    = "GameSubscriber"
+	TIME_SUB    = "TimeSubscriber"
+	STORE_SUB   = "EventStoreSubscriber"
+	SESSION_SUB = "SessionSubscriber"
+	STREAM_SUB  = "StreamSubscriber"
 )
 
 func GetSubscribers() map[string]Subscriber {
 	return map[string]Subscriber{
-		PLAYER_SUB: NewPlayerSubscriber(PLAYER_SUB),
-		GAME_SUB:   NewGameSubscriber(GAME_SUB),
-		TIME_SUB:   NewTimeSubscriber(TIME_SUB),
+		PLAYER_SUB:  NewPlayerSubscriber(PLAYER_SUB),
+		GAME_SUB:    NewGameSubscriber(GAME_SUB),
+		TIME_SUB:    NewTimeSubscriber(TIME_SUB),
+		STORE_SUB:   NewEventStoreSubscriber(STORE_SUB),
+		SESSION_SUB: NewSessionSubscriber(SESSION_SUB),
+		STREAM_SUB:  NewStreamSubscriber(STREAM_SUB),
 	}
 }
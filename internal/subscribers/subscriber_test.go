@@ -0,0 +1,59 @@
+package subscriber
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Bitstarz-eng/event-processing-challenge/internal/casino"
+)
+
+func TestWorkersHandleEachPlayerInOrder(t *testing.T) {
+	const players, events = 20, 5000
+
+	var mu sync.Mutex
+	handled := make(map[int][]int) // Player ID -> handled event IDs
+	bs := &BaseSubscriber{
+		Workers:  4,
+		ShardKey: ShardByPlayer,
+		EventHandler: func(event *casino.Event) {
+			// Slow handlers keep events queued until the workers are stopped
+			time.Sleep(10 * time.Microsecond)
+			mu.Lock()
+			handled[event.PlayerID] = append(handled[event.PlayerID], event.ID)
+			mu.Unlock()
+		},
+	}
+
+	queues, workers := bs.startWorkers()
+	shards := make(map[int]chan *casino.Event)
+	used := make(map[chan *casino.Event]bool)
+	for id := 1; id <= events; id++ {
+		// Negative IDs must still map to a queue
+		event := &casino.Event{ID: id, PlayerID: id%players - players/2}
+		queue := bs.queue(queues, event)
+		if shard, ok := shards[event.PlayerID]; ok && shard != queue {
+			t.Fatalf("events of player %d queued to different workers", event.PlayerID)
+		}
+		shards[event.PlayerID] = queue
+		used[queue] = true
+		queue <- event
+	}
+	stopWorkers(queues, workers)
+
+	if len(used) != len(queues) {
+		t.Fatalf("%d of %d workers used", len(used), len(queues))
+	}
+	total := 0
+	for playerID, ids := range handled {
+		total += len(ids)
+		for i := 1; i < len(ids); i++ {
+			if ids[i] < ids[i-1] {
+				t.Fatalf("event %d of player %d handled after event %d", ids[i], playerID, ids[i-1])
+			}
+		}
+	}
+	if total != events {
+		t.Fatalf("%d events handled after the workers stopped, want %d", total, events)
+	}
+}
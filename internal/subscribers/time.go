@@ -2,6 +2,7 @@ package subscriber
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 
 	"github.com/Bitstarz-eng/event-processing-challenge/internal/casino"
@@ -15,17 +16,20 @@ type TimeSubscriber struct {
 
 func NewTimeSubscriber(name string) Subscriber {
 	baseSubscriber := NewBaseSubscriber(name)
+	baseSubscriber.Workers = getWorkers("TIME_SUBSCRIBER_WORKERS")
+	baseSubscriber.ShardKey = ShardByEvent
 	ts := &TimeSubscriber{
 		BaseSubscriber: baseSubscriber,
 		Statistics:     statistics.NewTimeStats(),
 	}
 
 	ts.BaseSubscriber.EventHandler = ts.HandleEvent
+	ts.BaseSubscriber.Snapshotter = ts
 	return ts
 }
 
 func (ts *TimeSubscriber) Subscribe(ctx context.Context, channel, stopSignal string) {
-	ts.Statistics.ResetRedisKeys(ctx)
+	ts.Statistics.Reset(ctx)
 	ts.BaseSubscriber.Subscribe(ctx, channel, stopSignal)
 }
 
@@ -34,22 +38,35 @@ func (ts *TimeSubscriber) Unsubscribe(ctx context.Context, channel string) {
 }
 
 func (ts *TimeSubscriber) HandleEvent(event *casino.Event) {
-	ctx := context.Background()
-	timestamp := float64(event.CreatedAt.Unix())
-
-	// Increment total events
-	ts.Statistics.IncrementTotalEvents(ctx)
-	// Add timestamp to sorted set
-	ts.Statistics.AddEventPerMinute(ctx, timestamp, event.ID)
-	// Add timestamp to list and trim to last 60 seconds
-	ts.Statistics.AddMovingAvgPerSecond(ctx, timestamp)
+	// Count the event in the total and in the bucket of its second
+	ts.Statistics.AddEvent(context.Background(), event.CreatedAt)
 }
 
 func (ts *TimeSubscriber) GetStats() interface{} {
-	return ts.Statistics
+	return ts.Statistics.CalculateTimeStats()
+}
+
+func (ts *TimeSubscriber) SnapshotVersion() int {
+	return statistics.TIME_STATE_VERSION
+}
+
+func (ts *TimeSubscriber) SaveState() interface{} {
+	return ts.Statistics.State(context.Background())
+}
+
+func (ts *TimeSubscriber) RestoreState(data json.RawMessage) error {
+	var state statistics.TimeState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+	ts.Statistics.Restore(context.Background(), &state)
+	return nil
+}
+
+func (ts *TimeSubscriber) GetRejected() map[string]int64 {
+	return ts.BaseSubscriber.Rejected.Snapshot()
 }
 
 func (ts *TimeSubscriber) ShowStat() {
-	ts.Statistics.CalculateTimeStats()
-	fmt.Printf("Time Statistics:\n%v\n", ts.Statistics)
+	fmt.Printf("Time Statistics:\n%v\n", ts.Statistics.CalculateTimeStats())
 }
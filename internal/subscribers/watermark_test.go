@@ -0,0 +1,51 @@
+package subscriber
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Bitstarz-eng/event-processing-challenge/internal/casino"
+	"github.com/Bitstarz-eng/event-processing-challenge/internal/statistics"
+)
+
+func TestSubscribersAtDifferentSpeedsCountNoInOrderEventLate(t *testing.T) {
+	t.Setenv("TIME_STATS_STORE", statistics.TIME_STATS_STORE_MEMORY)
+	ps := &PlayerSubscriber{
+		BaseSubscriber: &BaseSubscriber{Rejected: statistics.NewValidationStats()},
+		Statistics:     statistics.NewPlayerAggregator(),
+	}
+	ts := &TimeSubscriber{
+		BaseSubscriber: &BaseSubscriber{Rejected: statistics.NewValidationStats()},
+		Statistics:     statistics.NewTimeStats(),
+	}
+
+	start := time.Now().Add(-time.Hour)
+	var events []*casino.Event
+	for id := 1; id <= 60; id++ {
+		events = append(events, &casino.Event{
+			ID:        id,
+			PlayerID:  10 + id%3,
+			GameID:    100,
+			Type:      casino.BET,
+			Amount:    100,
+			Currency:  "EUR",
+			AmountEUR: 100,
+			CreatedAt: start.Add(time.Duration(id) * time.Minute),
+		})
+	}
+
+	// The time subscriber handles every event before the player subscriber handles the first one
+	for _, event := range events {
+		ts.HandleEvent(event)
+	}
+	for _, event := range events {
+		ps.HandleEvent(event)
+	}
+
+	if late := ps.Statistics.Windows.LateEvents(); late != 0 {
+		t.Fatalf("player subscriber counted %d in-order events late", late)
+	}
+	if late := ts.Statistics.CalculateTimeStats().LateEvents; late != 0 {
+		t.Fatalf("time subscriber counted %d in-order events late", late)
+	}
+}